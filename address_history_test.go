@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"mintlayer_bot/fakeapi"
+)
+
+func TestTransferFor(t *testing.T) {
+	tx := TransactionInfo{
+		Inputs: []TxTransfer{{Destination: "me", Coins: AmountFromML(100)}},
+		Outputs: []TxTransfer{
+			{Destination: "alice", Coins: AmountFromML(30)},
+			{Destination: "bob", Coins: AmountFromML(20)},
+			{Destination: "alice", Coins: AmountFromML(5)},
+			{Destination: "me", Coins: AmountFromML(44)},
+		},
+	}
+	delta, counterparties := tx.transferFor("me")
+	if delta.Cmp(AmountFromML(-56)) != 0 || strings.Join(counterparties, ",") != "alice,bob" {
+		t.Fatalf("unexpected outgoing transfer %v to %v", delta, counterparties)
+	}
+	delta, counterparties = tx.transferFor("bob")
+	if delta.Cmp(AmountFromML(20)) != 0 || strings.Join(counterparties, ",") != "me" {
+		t.Fatalf("unexpected incoming transfer %v from %v", delta, counterparties)
+	}
+}
+
+func TestUnannouncedTxIDs(t *testing.T) {
+	history := []string{"t3", "t2", "t1"}
+	cases := []struct {
+		lastTxID string
+		balance  Amount
+		expected []string
+	}{
+		{"t1", AmountFromML(1), []string{"t3", "t2"}},
+		{"t3", AmountFromML(1), nil},
+		{"", Amount{}, history},
+		{"", AmountFromML(1), nil},
+		{"gone", AmountFromML(1), nil},
+	}
+	for _, c := range cases {
+		got := unannouncedTxIDs(history, c.lastTxID, c.balance)
+		if strings.Join(got, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("last %q, balance %v: expected %v, got %v", c.lastTxID, c.balance, c.expected, got)
+		}
+	}
+}
+
+func TestAddressHistoryAgainstFakeAPI(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	address := testTestnetAddress
+	server.SetAddress(address, fakeapi.Address{CoinBalance: AmountFromML(100).String()})
+	blockTime := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	for i := 1; i <= historyPageSize+2; i++ {
+		server.SetTransaction(fmt.Sprintf("tx%02d", i), fakeapi.Transaction{
+			BlockHeight: int64(i),
+			Time:        blockTime.Add(time.Duration(i) * time.Minute),
+			Inputs:      []fakeapi.Transfer{{Destination: "tmt1payer", Amount: AmountFromML(int64(i) + 1).String()}},
+			Outputs: []fakeapi.Transfer{
+				{Destination: address, Amount: AmountFromML(int64(i)).String()},
+				{Destination: "tmt1payer", Amount: AmountFromML(1).String()},
+			},
+		})
+	}
+	server.SetTransaction("pending", fakeapi.Transaction{
+		Inputs:  []fakeapi.Transfer{{Destination: address, Amount: AmountFromML(10).String()}},
+		Outputs: []fakeapi.Transfer{{Destination: "tmt1shop", Amount: AmountFromML(4).String()}, {Destination: "tmt1cafe", Amount: AmountFromML(5).String()}},
+	})
+	server.SetTransaction("unrelated", fakeapi.Transaction{
+		BlockHeight: 5,
+		Outputs:     []fakeapi.Transfer{{Destination: "tmt1shop", Amount: "1"}},
+	})
+
+	app := NewApp(&fakeStore{}, NewHTTPBalanceClient(server.URL), nil, NewNotificationManager(), "", context.Background())
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	command := func(text string) string {
+		t.Helper()
+		messages = nil
+		app.addressHistoryHandler(context.Background(), nil, &models.Update{
+			Message: &models.Message{
+				Text: text,
+				Chat: models.Chat{ID: 5},
+				From: &models.User{ID: 7},
+			},
+		})
+		return strings.Join(messages, "\n")
+	}
+
+	got := command("/address_history " + address)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != historyPageSize+2 {
+		t.Fatalf("expected a header, %d transactions and a next page hint, got %q", historyPageSize, got)
+	}
+	expected := []string{
+		"Transactions of `" + address + "` (page 1 of 2):",
+		"`pending`: sent 10 ML to `tmt1shop` and 1 other, in mempool",
+		"`tx12`: received 12 ML from `tmt1payer`, block 12, 2026-10-17 12:12:00 UTC",
+	}
+	for i, line := range expected {
+		if lines[i] != line {
+			t.Fatalf("line %d:\nexpected: %q\ngot:      %q", i, line, lines[i])
+		}
+	}
+	if last := lines[len(lines)-1]; last != "Next page: `/address_history "+address+" 2`" {
+		t.Fatalf("unexpected last line %q", last)
+	}
+
+	got = command("/address_history " + address + " 2")
+	expectedPage2 := "Transactions of `" + address + "` (page 2 of 2):\n" +
+		"`tx03`: received 3 ML from `tmt1payer`, block 3, 2026-10-17 12:03:00 UTC\n" +
+		"`tx02`: received 2 ML from `tmt1payer`, block 2, 2026-10-17 12:02:00 UTC\n" +
+		"`tx01`: received 1 ML from `tmt1payer`, block 1, 2026-10-17 12:01:00 UTC\n"
+	if got != expectedPage2 {
+		t.Fatalf("unexpected second page:\nexpected: %q\ngot:      %q", expectedPage2, got)
+	}
+	if got := command("/address_history " + address + " 3"); got != "`"+address+"` has only 2 pages of transactions" {
+		t.Fatalf("unexpected reply %q", got)
+	}
+}
+
+func TestAddressNotificationsListTransactions(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	address := testTestnetAddress
+	server.SetAddress(address, fakeapi.Address{CoinBalance: AmountFromML(5).String()})
+	server.SetTransaction("t1", fakeapi.Transaction{BlockHeight: 1, Outputs: []fakeapi.Transfer{{Destination: address, Amount: AmountFromML(5).String()}}})
+
+	store, _ := newTestSQLStore(t)
+	ctx := context.Background()
+	if err := store.AddMonitoredAddress(ctx, "7", address, testnetNetwork, 0, true, 5); err != nil {
+		t.Fatalf("AddMonitoredAddress failed: %v", err)
+	}
+	app := NewApp(store, NewHTTPBalanceClient(server.URL), nil, NewNotificationManager(), "", ctx)
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.notifyAddressesBalanceChanges(ctx, "7", 5, 0)
+	if len(messages) != 1 || messages[0] != "`"+address+"`: \\+5 ML\nTransactions: `t1`" {
+		t.Fatalf("unexpected notifications %q", messages)
+	}
+
+	messages = nil
+	server.SetAddress(address, fakeapi.Address{CoinBalance: AmountFromML(12).String()})
+	server.SetTransaction("t2", fakeapi.Transaction{BlockHeight: 2, Outputs: []fakeapi.Transfer{{Destination: address, Amount: AmountFromML(3).String()}}})
+	server.SetTransaction("t3", fakeapi.Transaction{BlockHeight: 3, Outputs: []fakeapi.Transfer{{Destination: address, Amount: AmountFromML(4).String()}}})
+	app.notifyAddressesBalanceChanges(ctx, "7", 5, 0)
+	if len(messages) != 1 || messages[0] != "`"+address+"`: \\+7 ML\nTransactions: `t3` `t2`" {
+		t.Fatalf("unexpected notifications %q", messages)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"mintlayer_bot/fakeapi"
+)
+
+func TestAddressTransferAlerts(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	address := testTestnetAddress
+	server.SetAddress(address, fakeapi.Address{CoinBalance: AmountFromML(5).String()})
+	server.SetTransaction("old", fakeapi.Transaction{BlockHeight: 1, Outputs: []fakeapi.Transfer{{Destination: address, Amount: AmountFromML(5).String()}}})
+
+	store, _ := newTestSQLStore(t)
+	ctx := context.Background()
+	if err := store.AddMonitoredAddress(ctx, "7", address, testnetNetwork, 0, true, 5); err != nil {
+		t.Fatalf("AddMonitoredAddress failed: %v", err)
+	}
+	var messages []string
+	newApp := func() *App {
+		app := NewApp(store, NewHTTPBalanceClient(server.URL), nil, NewNotificationManager(), "", ctx)
+		app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+			messages = append(messages, message)
+			return nil
+		}
+		return app
+	}
+	app := newApp()
+	command := func(text string) string {
+		t.Helper()
+		messages = nil
+		app.addressTransfersHandler(ctx, nil, &models.Update{
+			Message: &models.Message{
+				Text: text,
+				Chat: models.Chat{ID: 5},
+				From: &models.User{ID: 7},
+			},
+		})
+		return strings.Join(messages, "\n")
+	}
+	cycle := func() []string {
+		t.Helper()
+		messages = nil
+		app.notifyAddressTransfers(ctx, "7", 5)
+		return messages
+	}
+
+	if got := command("/address_transfers tmt1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq on"); !strings.HasPrefix(got, "Invalid address") {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	if got := command("/address_transfers " + address + " on"); !strings.HasPrefix(got, "Transfer alerts enabled") {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	if got := cycle(); len(got) != 0 {
+		t.Fatalf("expected earlier transactions not to be alerted, got %q", got)
+	}
+
+	pending := fakeapi.Transaction{
+		Inputs:  []fakeapi.Transfer{{Destination: address, Amount: AmountFromML(5).String()}},
+		Outputs: []fakeapi.Transfer{{Destination: "tmt1shop", Amount: AmountFromML(4).String()}},
+	}
+	server.SetTransaction("t1", pending)
+	if got := cycle(); len(got) != 1 || got[0] != "`"+address+"`: sent 5 ML to `tmt1shop`, in mempool\nTransaction `t1`" {
+		t.Fatalf("unexpected mempool alert %q", got)
+	}
+	if got := cycle(); len(got) != 0 {
+		t.Fatalf("expected the mempool alert not to repeat, got %q", got)
+	}
+
+	confirmed := pending
+	confirmed.BlockHeight = 7
+	confirmed.Time = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	server.SetTransaction("t1", confirmed)
+	server.SetTransaction("t2", fakeapi.Transaction{
+		BlockHeight: 7,
+		Inputs:      []fakeapi.Transfer{{Destination: "tmt1payer", Amount: AmountFromML(3).String()}},
+		Outputs:     []fakeapi.Transfer{{Destination: address, Amount: AmountFromML(2).String()}},
+	})
+	expected := []string{
+		"`" + address + "`: sent 5 ML to `tmt1shop`, confirmed at block 7\nTransaction `t1`",
+		"`" + address + "`: received 2 ML from `tmt1payer`, confirmed at block 7\nTransaction `t2`",
+	}
+	if got := cycle(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected confirmation alerts:\nexpected: %q\ngot:      %q", expected, got)
+	}
+
+	// Neither a restart nor a reorganisation that confirms t1 again repeats it.
+	app = newApp()
+	server.RemoveTransaction("t1")
+	if got := cycle(); len(got) != 0 {
+		t.Fatalf("expected no alerts after the reorg, got %q", got)
+	}
+	confirmed.BlockHeight = 8
+	server.SetTransaction("t1", confirmed)
+	if got := cycle(); len(got) != 0 {
+		t.Fatalf("expected the reconfirmed transaction not to be alerted again, got %q", got)
+	}
+
+	command("/address_transfers " + address + " off")
+	if addresses, err := store.GetTransferAlertAddresses(ctx, "7"); err != nil || len(addresses) != 0 {
+		t.Fatalf("expected transfer alerts to be off, got %v, %v", addresses, err)
+	}
+	if err := store.RemoveMonitoredAddress(ctx, "7", address); err != nil {
+		t.Fatalf("RemoveMonitoredAddress failed: %v", err)
+	}
+	if got := command("/address_transfers " + address + " on"); got != "You are not monitoring this address. Add it with `/address_add <address>` first." {
+		t.Fatalf("unexpected reply %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	mlDecimals            = 11
+	defaultAmountDecimals = mlDecimals
+)
+
+var atomsPerML = big.NewInt(PRECISION)
+
+// Amount is a quantity of ML kept losslessly in atoms. The zero value is a
+// valid amount of 0 ML. Amounts are immutable: arithmetic returns new values.
+type Amount struct {
+	atoms *big.Int
+}
+
+func AmountFromAtoms(atoms int64) Amount {
+	return Amount{atoms: big.NewInt(atoms)}
+}
+
+func AmountFromML(ml int64) Amount {
+	atoms := new(big.Int).Mul(big.NewInt(ml), atomsPerML)
+	return Amount{atoms: atoms}
+}
+
+func ParseAmountAtoms(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Amount{}, nil
+	}
+	atoms, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid atoms amount %q", s)
+	}
+	return Amount{atoms: atoms}, nil
+}
+
+func (a Amount) int() *big.Int {
+	if a.atoms == nil {
+		return new(big.Int)
+	}
+	return a.atoms
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{atoms: new(big.Int).Add(a.int(), b.int())}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{atoms: new(big.Int).Sub(a.int(), b.int())}
+}
+
+func (a Amount) Abs() Amount {
+	return Amount{atoms: new(big.Int).Abs(a.int())}
+}
+
+func (a Amount) Cmp(b Amount) int {
+	return a.int().Cmp(b.int())
+}
+
+func (a Amount) Sign() int {
+	return a.int().Sign()
+}
+
+func (a Amount) IsZero() bool {
+	return a.Sign() == 0
+}
+
+// MLFloat returns the amount in ML as a float, for estimates where precision
+// does not matter.
+func (a Amount) MLFloat() float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(a.int()), new(big.Float).SetInt(atomsPerML)).Float64()
+	return f
+}
+
+// AmountFromMLFloat is the inverse of MLFloat, truncated to whole atoms.
+func AmountFromMLFloat(ml float64) Amount {
+	atoms, _ := new(big.Float).Mul(big.NewFloat(ml), new(big.Float).SetInt(atomsPerML)).Int(nil)
+	return Amount{atoms: atoms}
+}
+
+// String returns the amount in atoms.
+func (a Amount) String() string {
+	return a.int().String()
+}
+
+// FormatML renders the amount in ML with thousands separators, truncated to
+// at most decimals fractional digits. Trailing zeros are dropped.
+func (a Amount) FormatML(decimals int) string {
+	return a.FormatUnits(mlDecimals, decimals)
+}
+
+// FormatUnits renders the amount for a currency with unitDecimals digits
+// per whole unit (11 for ML, per-token for fungible tokens), truncated to at
+// most decimals fractional digits.
+func (a Amount) FormatUnits(unitDecimals, decimals int) string {
+	if unitDecimals < 0 {
+		unitDecimals = 0
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+	if decimals > unitDecimals {
+		decimals = unitDecimals
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(unitDecimals)), nil)
+	abs := new(big.Int).Abs(a.int())
+	whole, frac := new(big.Int).QuoRem(abs, unit, new(big.Int))
+
+	out := groupThousands(whole.String())
+	fracDigits := ""
+	if unitDecimals > 0 {
+		fracDigits = fmt.Sprintf("%0*s", unitDecimals, frac.String())
+		fracDigits = strings.TrimRight(fracDigits[:decimals], "0")
+	}
+	if fracDigits != "" {
+		out += "." + fracDigits
+	}
+	if a.Sign() < 0 && (whole.Sign() != 0 || fracDigits != "") {
+		out = "-" + out
+	}
+	return out
+}
+
+func groupThousands(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var sb strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		sb.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(digits[i : i+3])
+	}
+	return sb.String()
+}
+
+// Scan implements sql.Scanner. Amounts are stored as decimal atom strings so
+// values beyond the int64 range survive the round trip.
+func (a *Amount) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*a = Amount{}
+		return nil
+	case int64:
+		*a = AmountFromAtoms(v)
+		return nil
+	case []byte:
+		parsed, err := ParseAmountAtoms(string(v))
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	case string:
+		parsed, err := ParseAmountAtoms(v)
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Amount", src)
+	}
+}
+
+// Value implements driver.Valuer.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestAmountFormatML(t *testing.T) {
+	tests := []struct {
+		amount   Amount
+		decimals int
+		expected string
+	}{
+		{Amount{}, 11, "0"},
+		{AmountFromML(1234567), 11, "1,234,567"},
+		{AmountFromAtoms(1), 11, "0.00000000001"},
+		{AmountFromAtoms(1), 2, "0"},
+		{AmountFromAtoms(150_000_000_000), 11, "1.5"},
+		{AmountFromAtoms(123_456_789_012), 3, "1.234"},
+		{AmountFromAtoms(-250_000_000_000), 11, "-2.5"},
+		{AmountFromAtoms(-1), 2, "0"},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.FormatML(tt.decimals); got != tt.expected {
+			t.Errorf("FormatML(%s, %d) = %q, expected %q", tt.amount, tt.decimals, got, tt.expected)
+		}
+	}
+}
+
+func TestParseAmountAtomsBeyondInt64(t *testing.T) {
+	amount, err := ParseAmountAtoms("40000000000000000000000")
+	if err != nil {
+		t.Fatalf("ParseAmountAtoms failed: %v", err)
+	}
+	if got := amount.FormatML(11); got != "400,000,000,000" {
+		t.Fatalf("unexpected ML value %q", got)
+	}
+	if _, err := ParseAmountAtoms("12.5"); err == nil {
+		t.Fatal("expected error for non-integer atoms")
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	a := AmountFromAtoms(10)
+	b := AmountFromAtoms(25)
+	if got := a.Sub(b); got.Cmp(AmountFromAtoms(-15)) != 0 {
+		t.Fatalf("unexpected difference %s", got)
+	}
+	if got := a.Sub(b).Abs(); got.Cmp(AmountFromAtoms(15)) != 0 {
+		t.Fatalf("unexpected absolute value %s", got)
+	}
+	if a.String() != "10" {
+		t.Fatalf("Sub mutated receiver: %s", a)
+	}
+}
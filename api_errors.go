@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrServer            = errors.New("api server error")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrMalformedResponse = errors.New("malformed response")
+	ErrUnexpectedStatus  = errors.New("unexpected status")
+)
+
+// ErrRetryBudgetExceeded is wrapped into the last error of a request that
+// was given up because the next retry would not fit in its time budget.
+var ErrRetryBudgetExceeded = errors.New("retry budget exceeded")
+
+// APIError describes a failed Mintlayer API call. Kind is one of the Err*
+// sentinels above so callers can use errors.Is to pick a reaction.
+type APIError struct {
+	Kind       error
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%v: %s", e.Kind, e.URL)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+func newStatusError(url string, resp *http.Response) *APIError {
+	apiErr := &APIError{URL: url, StatusCode: resp.StatusCode}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		apiErr.Kind = ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		apiErr.Kind = ErrRateLimited
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode >= 500:
+		apiErr.Kind = ErrServer
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	default:
+		apiErr.Kind = ErrUnexpectedStatus
+	}
+	return apiErr
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+func malformedResponse(url string, err error) *APIError {
+	return &APIError{Kind: ErrMalformedResponse, URL: url, Err: err}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAPIRequestsPerSecond = 20
+	defaultAPIMaxInFlight       = 10
+)
+
+// requestPriority orders waiting API requests: interactive commands go ahead
+// of background polling.
+type requestPriority int
+
+const (
+	priorityInteractive requestPriority = iota
+	priorityBackground
+)
+
+type priorityKey struct{}
+
+// withBackgroundPriority marks API requests made with ctx as background work
+// that yields to interactive commands.
+func withBackgroundPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priorityBackground)
+}
+
+func priorityOf(ctx context.Context) requestPriority {
+	if shared, ok := ctx.Value(sharedPriorityKey{}).(*sharedPriority); ok {
+		return shared.get()
+	}
+	if priority, ok := ctx.Value(priorityKey{}).(requestPriority); ok {
+		return priority
+	}
+	return priorityInteractive
+}
+
+type sharedPriorityKey struct{}
+
+// sharedPriority is the priority of a request made on behalf of several
+// callers. It is raised when a more urgent caller joins, and requests
+// already waiting in an apiLimiter move up with it.
+type sharedPriority struct {
+	mu       sync.Mutex
+	priority requestPriority
+	nextID   int
+	onRaise  map[int]func()
+}
+
+func newSharedPriority(priority requestPriority) *sharedPriority {
+	return &sharedPriority{priority: priority, onRaise: make(map[int]func())}
+}
+
+// withSharedPriority makes API requests made with ctx wait at p's current
+// priority.
+func withSharedPriority(ctx context.Context, p *sharedPriority) context.Context {
+	return context.WithValue(ctx, sharedPriorityKey{}, p)
+}
+
+func (p *sharedPriority) get() requestPriority {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.priority
+}
+
+// raise moves p up to priority; a lower one is ignored.
+func (p *sharedPriority) raise(priority requestPriority) {
+	p.mu.Lock()
+	if priority >= p.priority {
+		p.mu.Unlock()
+		return
+	}
+	p.priority = priority
+	hooks := make([]func(), 0, len(p.onRaise))
+	for _, hook := range p.onRaise {
+		hooks = append(hooks, hook)
+	}
+	p.mu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// watch calls hook after every raise until the returned stop is called.
+func (p *sharedPriority) watch(hook func()) (stop func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := p.nextID
+	p.nextID++
+	p.onRaise[id] = hook
+	return func() {
+		p.mu.Lock()
+		delete(p.onRaise, id)
+		p.mu.Unlock()
+	}
+}
+
+// apiLimiter is the process-wide cap on outbound API requests: at most
+// maxInFlight at once, started at least interval apart. Waiting requests are
+// served interactive first, then in arrival order.
+type apiLimiter struct {
+	interval    time.Duration
+	maxInFlight int
+
+	mu       sync.Mutex
+	inFlight int
+	next     time.Time
+	queues   [2][]*limiterWaiter
+	timer    *time.Timer
+}
+
+type limiterWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// newAPILimiter builds a limiter; a zero or negative setting disables that
+// limit.
+func newAPILimiter(requestsPerSecond float64, maxInFlight int) *apiLimiter {
+	l := &apiLimiter{maxInFlight: maxInFlight}
+	if requestsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return l
+}
+
+// acquire waits for a request slot. The returned release must be called
+// once the request, including reading its body, is done.
+func (l *apiLimiter) acquire(ctx context.Context) (func(), error) {
+	waiter := &limiterWaiter{ready: make(chan struct{})}
+	priority := priorityOf(ctx)
+
+	l.mu.Lock()
+	l.queues[priority] = append(l.queues[priority], waiter)
+	l.dispatchLocked()
+	l.mu.Unlock()
+
+	if shared, ok := ctx.Value(sharedPriorityKey{}).(*sharedPriority); ok {
+		requeue := func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if raised := shared.get(); raised < priority && !waiter.granted && l.removeLocked(priority, waiter) {
+				priority = raised
+				l.queues[priority] = append(l.queues[priority], waiter)
+				l.dispatchLocked()
+			}
+		}
+		defer shared.watch(requeue)()
+		// Catch a raise between reading the priority and watching it.
+		requeue()
+	}
+
+	select {
+	case <-waiter.ready:
+		return l.releaseFunc(), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if waiter.granted {
+			l.inFlight--
+			l.dispatchLocked()
+		} else {
+			l.removeLocked(priority, waiter)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (l *apiLimiter) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.inFlight--
+			l.dispatchLocked()
+			l.mu.Unlock()
+		})
+	}
+}
+
+// dispatchLocked grants slots to waiting requests while the limits allow,
+// and arms a timer when the rate limit is what holds the next one back.
+func (l *apiLimiter) dispatchLocked() {
+	for {
+		queue := -1
+		for i := range l.queues {
+			if len(l.queues[i]) > 0 {
+				queue = i
+				break
+			}
+		}
+		if queue < 0 {
+			return
+		}
+		if l.maxInFlight > 0 && l.inFlight >= l.maxInFlight {
+			return
+		}
+		now := time.Now()
+		if wait := l.next.Sub(now); wait > 0 {
+			if l.timer == nil {
+				l.timer = time.AfterFunc(wait, func() {
+					l.mu.Lock()
+					l.timer = nil
+					l.dispatchLocked()
+					l.mu.Unlock()
+				})
+			}
+			return
+		}
+
+		waiter := l.queues[queue][0]
+		l.queues[queue] = l.queues[queue][1:]
+		l.inFlight++
+		l.next = now.Add(l.interval)
+		waiter.granted = true
+		close(waiter.ready)
+	}
+}
+
+func (l *apiLimiter) removeLocked(priority requestPriority, waiter *limiterWaiter) bool {
+	queue := l.queues[priority]
+	for i, w := range queue {
+		if w == waiter {
+			l.queues[priority] = append(queue[:i:i], queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// limitedTransport applies an apiLimiter to every request of an http.Client.
+// The slot is held until the response body is closed.
+type limitedTransport struct {
+	limiter *apiLimiter
+	next    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
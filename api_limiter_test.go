@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPILimiterServesInteractiveFirst(t *testing.T) {
+	limiter := newAPILimiter(0, 1)
+	release, err := limiter.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	order := make(chan string, 2)
+	start := func(name string, ctx context.Context) {
+		go func() {
+			release, err := limiter.acquire(ctx)
+			if err != nil {
+				order <- "error"
+				return
+			}
+			order <- name
+			release()
+		}()
+	}
+	start("background", withBackgroundPriority(context.Background()))
+	waitForQueue(t, limiter, priorityBackground)
+	start("interactive", context.Background())
+	waitForQueue(t, limiter, priorityInteractive)
+
+	release()
+	if first, second := <-order, <-order; first != "interactive" || second != "background" {
+		t.Fatalf("expected interactive before background, got %s, %s", first, second)
+	}
+}
+
+func TestAPILimiterMovesRaisedSharedPriorityUp(t *testing.T) {
+	limiter := newAPILimiter(0, 1)
+	release, err := limiter.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	order := make(chan string, 2)
+	start := func(name string, ctx context.Context) {
+		go func() {
+			release, err := limiter.acquire(ctx)
+			if err != nil {
+				order <- "error"
+				return
+			}
+			order <- name
+			release()
+		}()
+	}
+	start("background", withBackgroundPriority(context.Background()))
+	waitForQueue(t, limiter, priorityBackground)
+	shared := newSharedPriority(priorityBackground)
+	start("shared", withSharedPriority(context.Background(), shared))
+	waitFor(t, func() bool {
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		return len(limiter.queues[priorityBackground]) == 2
+	})
+	shared.raise(priorityInteractive)
+	waitForQueue(t, limiter, priorityInteractive)
+
+	release()
+	if first, second := <-order, <-order; first != "shared" || second != "background" {
+		t.Fatalf("expected the raised request first, got %s, %s", first, second)
+	}
+}
+
+func waitForQueue(t *testing.T, limiter *apiLimiter, priority requestPriority) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		limiter.mu.Lock()
+		queued := len(limiter.queues[priority])
+		limiter.mu.Unlock()
+		if queued > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timeout waiting for a queued request")
+}
+
+func TestAPILimiterSpacesRequests(t *testing.T) {
+	limiter := newAPILimiter(50, 0)
+	begin := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := limiter.acquire(context.Background())
+		if err != nil {
+			t.Fatalf("acquire failed: %v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(begin); elapsed < 40*time.Millisecond {
+		t.Fatalf("expected requests 20ms apart, three took %s", elapsed)
+	}
+}
+
+func TestAPILimiterCancelledWaiterLeavesQueue(t *testing.T) {
+	limiter := newAPILimiter(0, 1)
+	release, _ := limiter.acquire(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := limiter.acquire(ctx)
+		done <- err
+	}()
+	waitForQueue(t, limiter, priorityInteractive)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	release()
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if limiter.inFlight != 0 || len(limiter.queues[priorityInteractive]) != 0 {
+		t.Fatalf("expected an idle limiter, inFlight=%d queued=%d", limiter.inFlight, len(limiter.queues[priorityInteractive]))
+	}
+}
+
+func TestLimitedTransportHoldsSlotUntilBodyClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	limiter := newAPILimiter(0, 1)
+	client := &http.Client{Transport: &limitedTransport{limiter: limiter}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the open response to hold the only slot, got %v", err)
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	release, err := limiter.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected a free slot after Close, got %v", err)
+	}
+	release()
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"regexp"
 	"strconv"
@@ -13,14 +15,25 @@ import (
 )
 
 type App struct {
-	store       Store
-	client      BalanceClient
-	bot         *bot.Bot
-	notify      *NotificationManager
-	adminUser   string
-	appCtx      context.Context
-	send        func(ctx context.Context, b *bot.Bot, chatID int64, message string) error
-	startNotify func(ctx context.Context, userID string, chatID int64)
+	store            Store
+	client           BalanceClient
+	bot              *bot.Bot
+	notify           *NotificationManager
+	adminUser        string
+	appCtx           context.Context
+	amountDecimals   int
+	networks         Networks
+	tip              *ChainTipWatcher
+	networkTips      map[string]*ChainTipWatcher
+	maxCheckInterval time.Duration
+	prices           PriceProvider
+	now              func() time.Time
+	send             func(ctx context.Context, b *bot.Bot, chatID int64, message string) error
+	startNotify      func(ctx context.Context, userID string, chatID int64)
+
+	// balanceConfirmations is how many blocks must follow the block a pool
+	// or delegation balance was read at before a change is announced.
+	balanceConfirmations int64
 }
 
 func NewApp(store Store, client BalanceClient, b *bot.Bot, notify *NotificationManager, adminUser string, appCtx context.Context) *App {
@@ -28,12 +41,16 @@ func NewApp(store Store, client BalanceClient, b *bot.Bot, notify *NotificationM
 		appCtx = context.Background()
 	}
 	app := &App{
-		store:  store,
-		client: client,
-		bot:    b,
-		notify: notify,
-		adminUser: adminUser,
-		appCtx: appCtx,
+		store:            store,
+		client:           client,
+		bot:              b,
+		notify:           notify,
+		adminUser:        adminUser,
+		appCtx:           appCtx,
+		amountDecimals:   defaultAmountDecimals,
+		networks:         defaultNetworks(),
+		maxCheckInterval: defaultMaxCheckInterval,
+		now:              time.Now,
 	}
 	app.send = defaultSendMessage
 	app.startNotify = app.notifyBalanceChangesRoutine
@@ -52,10 +69,99 @@ func (a *App) sendMessage(ctx context.Context, b *bot.Bot, chatID int64, message
 	}
 }
 
+// apiCircuitOpen reports whether the client's circuit breaker currently
+// refuses lookups, in which case a notification cycle is skipped.
+func (a *App) apiCircuitOpen() bool {
+	reporter, ok := a.client.(circuitStateReporter)
+	return ok && reporter.CircuitOpen()
+}
+
+// networkOf returns the network stored for id when it is tracked, and
+// otherwise the one its prefix names.
+func (a *App) networkOf(ctx context.Context, id string) (Network, error) {
+	return a.networks.Resolve(ctx, id, a.store.GetTrackedNetwork)
+}
+
+// networkLabel names the network stored for a tracked id in listings, e.g.
+// " (testnet)". It is empty for the primary network.
+func (a *App) networkLabel(ctx context.Context, id string) string {
+	network, err := a.networkOf(ctx, id)
+	if err != nil || network.Name == a.networks.Primary().Name {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", network.Name)
+}
+
+// tipFor returns the chain tip watcher of the network id belongs to, falling
+// back to the primary network's watcher.
+func (a *App) tipFor(ctx context.Context, id string) *ChainTipWatcher {
+	if network, err := a.networkOf(ctx, id); err == nil {
+		if tip, ok := a.networkTips[network.Name]; ok {
+			return tip
+		}
+	}
+	return a.tip
+}
+
+// heightFor is the tip height to report for a change of id. primaryHeight is
+// the primary network's height seen at the start of the check.
+func (a *App) heightFor(ctx context.Context, id string, primaryHeight int64) int64 {
+	tip := a.tipFor(ctx, id)
+	if tip == nil || tip == a.tip {
+		return primaryHeight
+	}
+	height, _ := tip.Tip()
+	return height
+}
+
+func (a *App) formatML(amount Amount) string {
+	return amount.FormatML(a.amountDecimals) + " ML"
+}
+
+// fiatRateFor returns the rate to the user's currency, or the zero rate
+// when fiat values are off or the price is unavailable, so a failing price
+// source never holds back a reply or notification.
+func (a *App) fiatRateFor(ctx context.Context, userID string) fiatRate {
+	if a.prices == nil {
+		return fiatRate{}
+	}
+	currency, err := a.store.GetUserCurrency(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting currency of user %s: %v", userID, err)
+		return fiatRate{}
+	}
+	if currency == "" {
+		return fiatRate{}
+	}
+	price, err := a.prices.Price(ctx, currency)
+	if err != nil {
+		log.Printf("Error getting the ML price in %s: %v", currency, err)
+		return fiatRate{}
+	}
+	return fiatRate{currency: currency, price: price}
+}
+
 func (a *App) sendCommandError(ctx context.Context, b *bot.Bot, chatID int64) {
 	a.sendMessage(ctx, b, chatID, "Something went wrong. Please try again later.")
 }
 
+// sendAPIError tells the user why a Mintlayer API lookup failed. Cancelled
+// requests (shutdown) are only logged.
+func (a *App) sendAPIError(ctx context.Context, b *bot.Bot, chatID int64, err error) {
+	switch {
+	case ctx.Err() != nil:
+		return
+	case errors.Is(err, ErrRateLimited):
+		a.sendMessage(ctx, b, chatID, "The Mintlayer API is rate limiting requests. Please try again in a minute.")
+	case errors.Is(err, ErrCircuitOpen):
+		a.sendMessage(ctx, b, chatID, "The Mintlayer API server is failing, requests are paused for a moment. Please try again in a minute.")
+	case errors.Is(err, ErrNotFound):
+		a.sendMessage(ctx, b, chatID, "Not found on the Mintlayer API server.")
+	default:
+		a.sendMessage(ctx, b, chatID, "The Mintlayer API server is unavailable. Please try again later.")
+	}
+}
+
 func defaultSendMessage(ctx context.Context, b *bot.Bot, chatID int64, message string) error {
 	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
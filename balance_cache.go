@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultCacheTTL = 15 * time.Second
+	// cacheSweepSize is the number of entries above which expired ones are
+	// dropped on the next store.
+	cacheSweepSize = 1024
+)
+
+// CacheStats counts how lookups through a CachingBalanceClient were served.
+type CacheStats struct {
+	// Hits were answered from the cache.
+	Hits uint64
+	// Coalesced waited for a request another caller already had in flight.
+	Coalesced uint64
+	// Misses sent a request to the API server.
+	Misses uint64
+}
+
+// cacheStatsReporter is implemented by clients that cache lookups.
+type cacheStatsReporter interface {
+	CacheStats() CacheStats
+}
+
+type freshAfterKey struct{}
+
+// withFreshAfter makes lookups with ctx ignore cached data fetched before t,
+// e.g. before the block the caller is checking for was seen. The result of
+// the fresh lookup is cached for everyone else.
+func withFreshAfter(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, freshAfterKey{}, t)
+}
+
+func freshAfterOf(ctx context.Context) time.Time {
+	t, _ := ctx.Value(freshAfterKey{}).(time.Time)
+	return t
+}
+
+// CachingBalanceClient keeps balance lookups for a short TTL and sends
+// concurrent lookups of the same ID as a single request. Chain tip and block
+// lookups are passed through: the tip watcher needs every poll to be fresh,
+// and it already keeps the recent blocks.
+type CachingBalanceClient struct {
+	next BalanceClient
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	flights map[string]*cacheFlight
+
+	hits      atomic.Uint64
+	coalesced atomic.Uint64
+	misses    atomic.Uint64
+}
+
+type cacheEntry struct {
+	value     any
+	fetchedAt time.Time
+}
+
+// cacheFlight is a request in progress. It runs detached from the caller
+// that started it and is cancelled once no caller waits for it any more.
+type cacheFlight struct {
+	done      chan struct{}
+	value     any
+	err       error
+	startedAt time.Time
+	waiters   int
+	cancel    context.CancelFunc
+	// priority is that of the most urgent waiter, so an interactive caller
+	// joining a background lookup does not wait behind background work.
+	priority *sharedPriority
+}
+
+// NewCachingBalanceClient wraps next; a zero or negative ttl keeps the
+// default.
+func NewCachingBalanceClient(next BalanceClient, ttl time.Duration) *CachingBalanceClient {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return &CachingBalanceClient{
+		next:    next,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+		flights: make(map[string]*cacheFlight),
+	}
+}
+
+func (c *CachingBalanceClient) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Coalesced: c.coalesced.Load(),
+		Misses:    c.misses.Load(),
+	}
+}
+
+// EndpointStatuses and CircuitOpen forward the wrapped client's diagnostics.
+func (c *CachingBalanceClient) EndpointStatuses() []EndpointStatus {
+	if reporter, ok := c.next.(endpointStatusReporter); ok {
+		return reporter.EndpointStatuses()
+	}
+	return nil
+}
+
+func (c *CachingBalanceClient) CircuitOpen() bool {
+	reporter, ok := c.next.(circuitStateReporter)
+	return ok && reporter.CircuitOpen()
+}
+
+// cachedLookup serves key from the cache when it is recent enough for ctx,
+// joins a matching request in flight, or else sends one. Errors are never
+// cached.
+func cachedLookup[T any](ctx context.Context, c *CachingBalanceClient, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	freshAfter := freshAfterOf(ctx)
+
+	c.mu.Lock()
+	now := c.now()
+	if entry, ok := c.entries[key]; ok && now.Sub(entry.fetchedAt) < c.ttl && !entry.fetchedAt.Before(freshAfter) {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return entry.value.(T), nil
+	}
+	flight, ok := c.flights[key]
+	if ok && !flight.startedAt.Before(freshAfter) {
+		c.coalesced.Add(1)
+		flight.priority.raise(priorityOf(ctx))
+	} else {
+		// A request started before the caller's freshness bound is no use to
+		// it; the new one replaces it for later callers.
+		c.misses.Add(1)
+		priority := newSharedPriority(priorityOf(ctx))
+		flightCtx, cancel := context.WithCancel(withSharedPriority(context.WithoutCancel(ctx), priority))
+		flight = &cacheFlight{done: make(chan struct{}), startedAt: now, cancel: cancel, priority: priority}
+		c.flights[key] = flight
+		go c.run(flightCtx, key, flight, func(ctx context.Context) (any, error) {
+			return fetch(ctx)
+		})
+	}
+	flight.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-flight.done:
+		if flight.err != nil {
+			return zero, flight.err
+		}
+		return flight.value.(T), nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		flight.waiters--
+		if flight.waiters == 0 {
+			flight.cancel()
+			if c.flights[key] == flight {
+				delete(c.flights, key)
+			}
+		}
+		c.mu.Unlock()
+		return zero, ctx.Err()
+	}
+}
+
+func (c *CachingBalanceClient) run(ctx context.Context, key string, flight *cacheFlight, fetch func(ctx context.Context) (any, error)) {
+	value, err := fetch(ctx)
+	flight.cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	flight.value, flight.err = value, err
+	close(flight.done)
+	if c.flights[key] == flight {
+		delete(c.flights, key)
+	}
+	if err != nil {
+		return
+	}
+	if entry, ok := c.entries[key]; ok && entry.fetchedAt.After(flight.startedAt) {
+		return
+	}
+	if len(c.entries) >= cacheSweepSize {
+		c.sweepLocked()
+	}
+	c.entries[key] = cacheEntry{value: value, fetchedAt: flight.startedAt}
+}
+
+func (c *CachingBalanceClient) sweepLocked() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *CachingBalanceClient) GetTipHeight(ctx context.Context) (int64, error) {
+	return c.next.GetTipHeight(ctx)
+}
+
+func (c *CachingBalanceClient) GetBlock(ctx context.Context, height int64) (BlockInfo, error) {
+	return c.next.GetBlock(ctx, height)
+}
+
+func (c *CachingBalanceClient) GetPoolStatus(ctx context.Context, poolID string) (PoolStatus, error) {
+	return cachedLookup(ctx, c, "pool_status:"+poolID, func(ctx context.Context) (PoolStatus, error) {
+		return c.next.GetPoolStatus(ctx, poolID)
+	})
+}
+
+func (c *CachingBalanceClient) GetPoolInfo(ctx context.Context, poolID string) (PoolInfo, error) {
+	return cachedLookup(ctx, c, "pool_info:"+poolID, func(ctx context.Context) (PoolInfo, error) {
+		return c.next.GetPoolInfo(ctx, poolID)
+	})
+}
+
+func (c *CachingBalanceClient) GetPoolDelegations(ctx context.Context, poolID string) ([]DelegationInfo, error) {
+	return cachedLookup(ctx, c, "pool_delegations:"+poolID, func(ctx context.Context) ([]DelegationInfo, error) {
+		return c.next.GetPoolDelegations(ctx, poolID)
+	})
+}
+
+func (c *CachingBalanceClient) GetDelegationBalance(ctx context.Context, delegationID string) (Amount, error) {
+	return cachedLookup(ctx, c, "delegation_balance:"+delegationID, func(ctx context.Context) (Amount, error) {
+		return c.next.GetDelegationBalance(ctx, delegationID)
+	})
+}
+
+func (c *CachingBalanceClient) GetDelegationInfo(ctx context.Context, delegationID string) (DelegationInfo, error) {
+	return cachedLookup(ctx, c, "delegation_info:"+delegationID, func(ctx context.Context) (DelegationInfo, error) {
+		return c.next.GetDelegationInfo(ctx, delegationID)
+	})
+}
+
+func (c *CachingBalanceClient) GetAddressBalance(ctx context.Context, address string) (AddressBalance, error) {
+	return cachedLookup(ctx, c, "address_balance:"+address, func(ctx context.Context) (AddressBalance, error) {
+		return c.next.GetAddressBalance(ctx, address)
+	})
+}
+
+func (c *CachingBalanceClient) GetAddressHistory(ctx context.Context, address string, offset, count int) (AddressHistory, error) {
+	key := fmt.Sprintf("address_history:%s:%d:%d", address, offset, count)
+	return cachedLookup(ctx, c, key, func(ctx context.Context) (AddressHistory, error) {
+		return c.next.GetAddressHistory(ctx, address, offset, count)
+	})
+}
+
+// GetAddressTransactions is not cached: it is only asked for transactions
+// not yet alerted, and a mempool transaction must be seen confirming.
+func (c *CachingBalanceClient) GetAddressTransactions(ctx context.Context, address string, txIDs []string) ([]TransactionInfo, error) {
+	return c.next.GetAddressTransactions(ctx, address, txIDs)
+}
+
+func (c *CachingBalanceClient) GetAddressDelegations(ctx context.Context, address string) ([]DelegationInfo, error) {
+	return cachedLookup(ctx, c, "address_delegations:"+address, func(ctx context.Context) ([]DelegationInfo, error) {
+		return c.next.GetAddressDelegations(ctx, address)
+	})
+}
+
+func (c *CachingBalanceClient) GetAddressPools(ctx context.Context, address string) ([]PoolInfo, error) {
+	return cachedLookup(ctx, c, "address_pools:"+address, func(ctx context.Context) ([]PoolInfo, error) {
+		return c.next.GetAddressPools(ctx, address)
+	})
+}
+
+// GetTotalStake is not cached here; the HTTP client underneath keeps the
+// pool list it is summed from for poolListTTL.
+func (c *CachingBalanceClient) GetTotalStake(ctx context.Context, poolID string) (Amount, error) {
+	return c.next.GetTotalStake(ctx, poolID)
+}
+
+// GetTokenInfo is cached like the balances; the HTTP client underneath keeps
+// token metadata for the life of the process anyway.
+func (c *CachingBalanceClient) GetTokenInfo(ctx context.Context, tokenID string) (TokenInfo, error) {
+	return cachedLookup(ctx, c, "token_info:"+tokenID, func(ctx context.Context) (TokenInfo, error) {
+		return c.next.GetTokenInfo(ctx, tokenID)
+	})
+}
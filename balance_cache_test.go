@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingBalanceClient counts delegation balance lookups and blocks them
+// until release is closed.
+type countingBalanceClient struct {
+	noopBalanceClient
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (c *countingBalanceClient) GetDelegationBalance(ctx context.Context, delegationID string) (Amount, error) {
+	calls := c.calls.Add(1)
+	if c.release != nil {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return Amount{}, ctx.Err()
+		}
+	}
+	if c.err != nil {
+		return Amount{}, c.err
+	}
+	return AmountFromAtoms(int64(calls)), nil
+}
+
+func TestCachingBalanceClientCoalescesConcurrentLookups(t *testing.T) {
+	next := &countingBalanceClient{release: make(chan struct{})}
+	client := NewCachingBalanceClient(next, time.Minute)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([]Amount, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			amount, err := client.GetDelegationBalance(context.Background(), testMainnetDelegationID)
+			if err != nil {
+				t.Errorf("lookup %d: %v", i, err)
+			}
+			results[i] = amount
+		}(i)
+	}
+	waitFor(t, func() bool { return client.CacheStats().Misses+client.CacheStats().Coalesced == callers })
+	close(next.release)
+	wg.Wait()
+
+	if got := next.calls.Load(); got != 1 {
+		t.Fatalf("expected one request, got %d", got)
+	}
+	for i, amount := range results {
+		if amount.Cmp(AmountFromAtoms(1)) != 0 {
+			t.Fatalf("caller %d got %s", i, amount)
+		}
+	}
+	if stats := client.CacheStats(); stats.Misses != 1 || stats.Coalesced != callers-1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCachingBalanceClientExpiresAndBypasses(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	next := &countingBalanceClient{}
+	client := NewCachingBalanceClient(next, 15*time.Second)
+	client.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	lookup := func(ctx context.Context) string {
+		t.Helper()
+		amount, err := client.GetDelegationBalance(ctx, testMainnetDelegationID)
+		if err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+		return amount.String()
+	}
+
+	if got := lookup(ctx); got != "1" {
+		t.Fatalf("first lookup got %s", got)
+	}
+	now = now.Add(10 * time.Second)
+	if got := lookup(ctx); got != "1" {
+		t.Fatalf("expected a cache hit, got %s", got)
+	}
+	if got := lookup(withFreshAfter(ctx, now.Add(-time.Second))); got != "2" {
+		t.Fatalf("expected a fresh lookup to skip the cache, got %s", got)
+	}
+	if got := lookup(withFreshAfter(ctx, now.Add(-time.Second))); got != "2" {
+		t.Fatalf("expected the fresh result to be cached, got %s", got)
+	}
+	now = now.Add(15 * time.Second)
+	if got := lookup(ctx); got != "3" {
+		t.Fatalf("expected the entry to expire, got %s", got)
+	}
+	if stats := client.CacheStats(); stats.Hits != 2 || stats.Misses != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCachingBalanceClientDoesNotCacheErrors(t *testing.T) {
+	next := &countingBalanceClient{err: &APIError{Kind: ErrServer, StatusCode: 503}}
+	client := NewCachingBalanceClient(next, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetDelegationBalance(context.Background(), testMainnetDelegationID); !errors.Is(err, ErrServer) {
+			t.Fatalf("expected ErrServer, got %v", err)
+		}
+	}
+	if got := next.calls.Load(); got != 2 {
+		t.Fatalf("expected errors to be retried, got %d requests", got)
+	}
+}
+
+func TestCachingBalanceClientCancelledCallerLeavesOthersWaiting(t *testing.T) {
+	next := &countingBalanceClient{release: make(chan struct{})}
+	client := NewCachingBalanceClient(next, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.GetDelegationBalance(ctx, testMainnetDelegationID)
+		first <- err
+	}()
+	waitFor(t, func() bool { return next.calls.Load() == 1 })
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := client.GetDelegationBalance(context.Background(), testMainnetDelegationID)
+		second <- err
+	}()
+	waitFor(t, func() bool { return client.CacheStats().Coalesced == 1 })
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled caller to stop, got %v", err)
+	}
+	close(next.release)
+	if err := <-second; err != nil {
+		t.Fatalf("expected the remaining caller to get the result, got %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// priorityBalanceClient reports the priority its delegation lookups run at
+// once release is closed.
+type priorityBalanceClient struct {
+	noopBalanceClient
+	release  chan struct{}
+	priority chan requestPriority
+}
+
+func (c *priorityBalanceClient) GetDelegationBalance(ctx context.Context, delegationID string) (Amount, error) {
+	<-c.release
+	c.priority <- priorityOf(ctx)
+	return Amount{}, nil
+}
+
+func TestCachingBalanceClientRaisesFlightToInteractive(t *testing.T) {
+	next := &priorityBalanceClient{release: make(chan struct{}), priority: make(chan requestPriority, 1)}
+	client := NewCachingBalanceClient(next, time.Minute)
+
+	var wg sync.WaitGroup
+	lookup := func(ctx context.Context) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = client.GetDelegationBalance(ctx, testMainnetDelegationID)
+		}()
+	}
+	lookup(withBackgroundPriority(context.Background()))
+	waitFor(t, func() bool { return client.CacheStats().Misses == 1 })
+	lookup(context.Background())
+	waitFor(t, func() bool { return client.CacheStats().Coalesced == 1 })
+	close(next.release)
+	wg.Wait()
+
+	if priority := <-next.priority; priority != priorityInteractive {
+		t.Fatalf("expected the shared lookup to run at interactive priority, got %v", priority)
+	}
+}
@@ -1,28 +1,286 @@
 package main
 
-import "strings"
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
 
+// BalanceClient looks up on-chain balances. Failures are returned as
+// *APIError values wrapping ErrNotFound, ErrServer, ErrRateLimited or
+// ErrMalformedResponse; a nil error always means the balance is real.
+// Unknown pools are not an error: GetPoolStatus and GetPoolInfo report
+// PoolStateNotFound.
 type BalanceClient interface {
-	GetPoolBalance(poolID string) (int64, error)
-	GetDelegationBalance(delegationID string) (int64, error)
+	GetTipHeight(ctx context.Context) (int64, error)
+	GetBlock(ctx context.Context, height int64) (BlockInfo, error)
+	GetPoolStatus(ctx context.Context, poolID string) (PoolStatus, error)
+	GetPoolInfo(ctx context.Context, poolID string) (PoolInfo, error)
+	GetPoolDelegations(ctx context.Context, poolID string) ([]DelegationInfo, error)
+	GetDelegationBalance(ctx context.Context, delegationID string) (Amount, error)
+	GetDelegationInfo(ctx context.Context, delegationID string) (DelegationInfo, error)
+	GetAddressBalance(ctx context.Context, address string) (AddressBalance, error)
+	GetAddressHistory(ctx context.Context, address string, offset, count int) (AddressHistory, error)
+	GetAddressTransactions(ctx context.Context, address string, txIDs []string) ([]TransactionInfo, error)
+	GetAddressDelegations(ctx context.Context, address string) ([]DelegationInfo, error)
+	GetAddressPools(ctx context.Context, address string) ([]PoolInfo, error)
+	GetTotalStake(ctx context.Context, poolID string) (Amount, error)
+	GetTokenInfo(ctx context.Context, tokenID string) (TokenInfo, error)
 }
 
+// HTTPBalanceClient talks to the API servers of one network, in order of
+// preference, failing over to the next one when a server is down.
 type HTTPBalanceClient struct {
-	baseURL string
+	endpoints  *endpointSet
+	breaker    *circuitBreaker
+	httpClient *http.Client
+
+	tokensMu sync.Mutex
+	tokens   map[string]TokenInfo
+
+	poolsMu     sync.Mutex
+	pools       []PoolInfo
+	poolsListed time.Time
+}
+
+// poolListTTL is how long the list of all pools is reused by
+// GetAddressPools.
+const poolListTTL = 10 * time.Minute
+
+func NewHTTPBalanceClient(baseURLs ...string) *HTTPBalanceClient {
+	var cleaned []string
+	for _, baseURL := range baseURLs {
+		if baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/"); baseURL != "" {
+			cleaned = append(cleaned, baseURL)
+		}
+	}
+	if len(cleaned) == 0 {
+		cleaned = []string{defaultAPIBaseURL}
+	}
+	return &HTTPBalanceClient{
+		endpoints:  newEndpointSet(cleaned),
+		breaker:    newCircuitBreaker(defaultBreakerFailures, defaultBreakerCooldown),
+		httpClient: httpClient,
+		tokens:     make(map[string]TokenInfo),
+	}
+}
+
+// RunHealthChecks probes every endpoint through the blocks endpoint until ctx
+// is cancelled, bringing failed servers back into rotation once they answer.
+func (c *HTTPBalanceClient) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	ctx = withBackgroundPriority(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, status := range c.endpoints.statuses() {
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			_, err := getBlocksWithBaseURL(checkCtx, c.httpClient, status.BaseURL)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			c.endpoints.mark(status.BaseURL, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *HTTPBalanceClient) EndpointStatuses() []EndpointStatus {
+	statuses := c.endpoints.statuses()
+	for i := range statuses {
+		statuses[i].Circuit = c.breaker.State().String()
+	}
+	return statuses
+}
+
+// SetCircuitBreaker replaces the circuit breaker settings; call it before
+// the client is used.
+func (c *HTTPBalanceClient) SetCircuitBreaker(failures int, cooldown time.Duration) {
+	c.breaker = newCircuitBreaker(failures, cooldown)
 }
 
-func NewHTTPBalanceClient(baseURL string) *HTTPBalanceClient {
-	baseURL = strings.TrimRight(baseURL, "/")
-	if baseURL == "" {
-		baseURL = defaultAPIBaseURL
+// CircuitOpen reports whether lookups currently fail fast with
+// ErrCircuitOpen.
+func (c *HTTPBalanceClient) CircuitOpen() bool {
+	return c.breaker.Open()
+}
+
+// callAPI runs a lookup through the circuit breaker and endpoint failover.
+func callAPI[T any](ctx context.Context, c *HTTPBalanceClient, call func(baseURL string) (T, error)) (T, error) {
+	var zero T
+	if err := c.breaker.allow(); err != nil {
+		return zero, &APIError{Kind: ErrCircuitOpen, URL: c.endpoints.active()}
+	}
+	result, err := withFailover(ctx, c.endpoints, call)
+	if ctx.Err() != nil {
+		c.breaker.release()
+		return result, err
 	}
-	return &HTTPBalanceClient{baseURL: baseURL}
+	c.breaker.record(err)
+	return result, err
+}
+
+func (c *HTTPBalanceClient) GetTipHeight(ctx context.Context) (int64, error) {
+	return callAPI(ctx, c, func(baseURL string) (int64, error) {
+		return getBlocksWithBaseURL(ctx, c.httpClient, baseURL)
+	})
+}
+
+func (c *HTTPBalanceClient) GetBlock(ctx context.Context, height int64) (BlockInfo, error) {
+	return callAPI(ctx, c, func(baseURL string) (BlockInfo, error) {
+		return getBlockAtHeightWithBaseURL(ctx, c.httpClient, baseURL, height)
+	})
+}
+
+func (c *HTTPBalanceClient) GetPoolStatus(ctx context.Context, poolID string) (PoolStatus, error) {
+	return callAPI(ctx, c, func(baseURL string) (PoolStatus, error) {
+		return getPoolStatusWithBaseURL(ctx, c.httpClient, baseURL, poolID)
+	})
+}
+
+// GetPoolInfo also reports the tip of the server the pool was read from, so
+// a lagging server's balance is not taken for the current one.
+func (c *HTTPBalanceClient) GetPoolInfo(ctx context.Context, poolID string) (PoolInfo, error) {
+	return callAPI(ctx, c, func(baseURL string) (PoolInfo, error) {
+		height, err := getBlocksWithBaseURL(ctx, c.httpClient, baseURL)
+		if err != nil {
+			return PoolInfo{}, err
+		}
+		info, err := getPoolInfoWithBaseURL(ctx, c.httpClient, baseURL, poolID)
+		info.Height = height
+		return info, err
+	})
 }
 
-func (c *HTTPBalanceClient) GetPoolBalance(poolID string) (int64, error) {
-	return getPoolBalanceWithBaseURL(c.baseURL, poolID)
+func (c *HTTPBalanceClient) GetPoolDelegations(ctx context.Context, poolID string) ([]DelegationInfo, error) {
+	return callAPI(ctx, c, func(baseURL string) ([]DelegationInfo, error) {
+		return getPoolDelegationsWithBaseURL(ctx, c.httpClient, baseURL, poolID)
+	})
 }
 
-func (c *HTTPBalanceClient) GetDelegationBalance(delegationID string) (int64, error) {
-	return getDelegationBalanceWithBaseURL(c.baseURL, delegationID)
+func (c *HTTPBalanceClient) GetDelegationBalance(ctx context.Context, delegationID string) (Amount, error) {
+	return callAPI(ctx, c, func(baseURL string) (Amount, error) {
+		return getDelegationBalanceWithBaseURL(ctx, c.httpClient, baseURL, delegationID)
+	})
+}
+
+// GetDelegationInfo also reports the tip of the server the delegation was
+// read from, like GetPoolInfo.
+func (c *HTTPBalanceClient) GetDelegationInfo(ctx context.Context, delegationID string) (DelegationInfo, error) {
+	return callAPI(ctx, c, func(baseURL string) (DelegationInfo, error) {
+		height, err := getBlocksWithBaseURL(ctx, c.httpClient, baseURL)
+		if err != nil {
+			return DelegationInfo{}, err
+		}
+		info, err := getDelegationInfoWithBaseURL(ctx, c.httpClient, baseURL, delegationID)
+		info.Height = height
+		return info, err
+	})
+}
+
+func (c *HTTPBalanceClient) GetAddressBalance(ctx context.Context, address string) (AddressBalance, error) {
+	return callAPI(ctx, c, func(baseURL string) (AddressBalance, error) {
+		return getAddressBalanceWithBaseURL(ctx, c.httpClient, baseURL, address)
+	})
+}
+
+func (c *HTTPBalanceClient) GetAddressHistory(ctx context.Context, address string, offset, count int) (AddressHistory, error) {
+	return callAPI(ctx, c, func(baseURL string) (AddressHistory, error) {
+		return getAddressHistoryWithBaseURL(ctx, c.httpClient, baseURL, address, offset, count)
+	})
+}
+
+// GetAddressTransactions looks up transactions from the history of address.
+// The address only picks the network; transaction IDs do not carry one.
+func (c *HTTPBalanceClient) GetAddressTransactions(ctx context.Context, address string, txIDs []string) ([]TransactionInfo, error) {
+	return callAPI(ctx, c, func(baseURL string) ([]TransactionInfo, error) {
+		return getTransactionsWithBaseURL(ctx, c.httpClient, baseURL, txIDs)
+	})
+}
+
+func (c *HTTPBalanceClient) GetAddressDelegations(ctx context.Context, address string) ([]DelegationInfo, error) {
+	return callAPI(ctx, c, func(baseURL string) ([]DelegationInfo, error) {
+		return getAddressDelegationsWithBaseURL(ctx, c.httpClient, baseURL, address)
+	})
+}
+
+// GetAddressPools returns the pools whose staker or decommission
+// destination is address. The whole list of pools is fetched at most once
+// per poolListTTL, so it is meant for occasional commands rather than
+// notification cycles.
+func (c *HTTPBalanceClient) GetAddressPools(ctx context.Context, address string) ([]PoolInfo, error) {
+	pools, err := c.poolList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var owned []PoolInfo
+	for _, pool := range pools {
+		if pool.StakerDestination == address || pool.DecommissionDestination == address {
+			owned = append(owned, pool)
+		}
+	}
+	return owned, nil
+}
+
+// GetTotalStake is the stake of all active pools on the network, from the
+// same list as GetAddressPools. poolID only selects the network.
+func (c *HTTPBalanceClient) GetTotalStake(ctx context.Context, poolID string) (Amount, error) {
+	pools, err := c.poolList(ctx)
+	if err != nil {
+		return Amount{}, err
+	}
+	var total Amount
+	for _, pool := range pools {
+		if pool.State == PoolStateActive {
+			total = total.Add(pool.TotalStake())
+		}
+	}
+	return total, nil
+}
+
+func (c *HTTPBalanceClient) poolList(ctx context.Context) ([]PoolInfo, error) {
+	c.poolsMu.Lock()
+	defer c.poolsMu.Unlock()
+	if !c.poolsListed.IsZero() && time.Since(c.poolsListed) < poolListTTL {
+		return c.pools, nil
+	}
+	pools, err := callAPI(ctx, c, func(baseURL string) ([]PoolInfo, error) {
+		return getPoolListWithBaseURL(ctx, c.httpClient, baseURL)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.pools, c.poolsListed = pools, time.Now()
+	return pools, nil
+}
+
+// GetTokenInfo caches token metadata for the life of the process; a token's
+// ticker and number of decimals are fixed when it is issued.
+func (c *HTTPBalanceClient) GetTokenInfo(ctx context.Context, tokenID string) (TokenInfo, error) {
+	c.tokensMu.Lock()
+	info, ok := c.tokens[tokenID]
+	c.tokensMu.Unlock()
+	if ok {
+		return info, nil
+	}
+
+	info, err := callAPI(ctx, c, func(baseURL string) (TokenInfo, error) {
+		return getTokenInfoWithBaseURL(ctx, c.httpClient, baseURL, tokenID)
+	})
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	c.tokensMu.Lock()
+	c.tokens[tokenID] = info
+	c.tokensMu.Unlock()
+	return info, nil
 }
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -12,36 +14,166 @@ import (
 type fakeStore struct {
 	pools          []string
 	delegations    []string
+	addresses      []MonitoredAddress
 	notifications  []Notification
 	removeByChatID func(chatID int64) error
 }
 
-func (f *fakeStore) AddMonitoredAddress(ctx context.Context, userID, address string, threshold int, notifyOnChange bool, chatID int64) error {
+func (f *fakeStore) AddMonitoredAddress(ctx context.Context, userID, address, network string, threshold int, notifyOnChange bool, chatID int64) error {
 	return nil
 }
 
-func (f *fakeStore) AddPool(ctx context.Context, userID, poolID string) error    { return nil }
-func (f *fakeStore) RemovePool(ctx context.Context, userID, poolID string) error { return nil }
+func (f *fakeStore) RemoveMonitoredAddress(ctx context.Context, userID, address string) error {
+	return nil
+}
+func (f *fakeStore) GetMonitoredAddresses(ctx context.Context, userID string) ([]MonitoredAddress, error) {
+	return f.addresses, nil
+}
+func (f *fakeStore) UpdateAddressBalance(ctx context.Context, userID, address string, balance Amount) error {
+	return nil
+}
+func (f *fakeStore) UpdateAddressLastTx(ctx context.Context, userID, address, txID string) error {
+	return nil
+}
+func (f *fakeStore) SetAddressTransferAlerts(ctx context.Context, userID, address string, enabled bool, knownTxIDs []string) error {
+	return nil
+}
+func (f *fakeStore) GetTransferAlertAddresses(ctx context.Context, userID string) ([]string, error) {
+	return nil, nil
+}
+func (f *fakeStore) GetAddressTransfers(ctx context.Context, userID, address string) (map[string]bool, error) {
+	return map[string]bool{}, nil
+}
+func (f *fakeStore) MarkAddressTransfer(ctx context.Context, userID, address, txID string, confirmed bool) error {
+	return nil
+}
+
+func (f *fakeStore) GetAddressTokenBalances(ctx context.Context, userID, address string) (map[string]Amount, error) {
+	return map[string]Amount{}, nil
+}
+func (f *fakeStore) UpdateAddressTokenBalance(ctx context.Context, userID, address, tokenID string, balance Amount) error {
+	return nil
+}
+
+func (f *fakeStore) SetPoolBlockNotifications(ctx context.Context, userID, poolID string, enabled bool, fromHeight int64) error {
+	return nil
+}
+func (f *fakeStore) GetBlockNotificationPools(ctx context.Context, userID string) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+func (f *fakeStore) UpdatePoolLastBlock(ctx context.Context, userID, poolID string, height int64) error {
+	return nil
+}
+
+func (f *fakeStore) SetPoolLiveness(ctx context.Context, userID, poolID string, enabled bool, window time.Duration, since BlockInfo) error {
+	return nil
+}
+func (f *fakeStore) GetPoolLiveness(ctx context.Context, userID string) ([]PoolLiveness, error) {
+	return nil, nil
+}
+func (f *fakeStore) UpdatePoolLiveness(ctx context.Context, userID, poolID string, height int64, lastBlockTime time.Time, alerted bool) error {
+	return nil
+}
+func (f *fakeStore) AddPoolSample(ctx context.Context, sample PoolSample, minInterval time.Duration) error {
+	return nil
+}
+func (f *fakeStore) GetPoolSamples(ctx context.Context, poolID string, since time.Time) ([]PoolSample, error) {
+	return nil, nil
+}
+func (f *fakeStore) PrunePoolSamples(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func (f *fakeStore) SetPoolDelegationAlerts(ctx context.Context, userID, poolID string, enabled bool, threshold Amount, delegations map[string]Amount) error {
+	return nil
+}
+
+func (f *fakeStore) GetDelegationAlertPools(ctx context.Context, userID string) (map[string]Amount, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) GetPoolDelegationBalances(ctx context.Context, userID, poolID string) (map[string]Amount, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) ReplacePoolDelegationBalances(ctx context.Context, userID, poolID string, delegations map[string]Amount) error {
+	return nil
+}
+
+func (f *fakeStore) ImportPoolsAndDelegations(ctx context.Context, userID, network string, poolIDs, delegationIDs []string) error {
+	return nil
+}
+
+func (f *fakeStore) SetAddressSync(ctx context.Context, userID, address, network string, enabled bool) error {
+	return nil
+}
+
+func (f *fakeStore) GetSyncedAddresses(ctx context.Context, userID string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) SetUserCurrency(ctx context.Context, userID, currency string) error {
+	return nil
+}
+
+func (f *fakeStore) GetUserCurrency(ctx context.Context, userID string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeStore) AddPool(ctx context.Context, userID, poolID, network string) error { return nil }
+func (f *fakeStore) RemovePool(ctx context.Context, userID, poolID string) error       { return nil }
+func (f *fakeStore) GetTrackedNetwork(ctx context.Context, id string) (string, error) {
+	return "", sql.ErrNoRows
+}
+
+func (f *fakeStore) GetTrackedPools(ctx context.Context) ([]string, error) {
+	return f.pools, nil
+}
+
 func (f *fakeStore) GetPools(ctx context.Context, userID string) ([]string, error) {
 	return f.pools, nil
 }
-func (f *fakeStore) AddDelegation(ctx context.Context, userID, delegationID string) error { return nil }
+func (f *fakeStore) AddDelegation(ctx context.Context, userID, delegationID, network string) error {
+	return nil
+}
 func (f *fakeStore) RemoveDelegation(ctx context.Context, userID, delegationID string) error {
 	return nil
 }
 func (f *fakeStore) GetDelegations(ctx context.Context, userID string) ([]string, error) {
 	return f.delegations, nil
 }
-func (f *fakeStore) GetPoolBalance(ctx context.Context, userID, poolID string) (int64, error) {
-	return 0, nil
+func (f *fakeStore) GetPoolBalance(ctx context.Context, userID, poolID string) (Amount, error) {
+	return Amount{}, nil
+}
+func (f *fakeStore) AddBalanceObservation(ctx context.Context, userID, kind, id string, observation BalanceObservation) error {
+	return nil
+}
+
+func (f *fakeStore) GetBalanceObservations(ctx context.Context, userID, kind, id string) ([]BalanceObservation, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) RemoveBalanceObservation(ctx context.Context, userID, kind, id string, height int64) error {
+	return nil
+}
+
+func (f *fakeStore) SettleBalanceObservation(ctx context.Context, userID, kind, id string, height int64) error {
+	return nil
+}
+
+func (f *fakeStore) UpdatePoolBalance(ctx context.Context, userID, poolID string, balance Amount) error {
+	return nil
 }
-func (f *fakeStore) UpdatePoolBalance(ctx context.Context, userID, poolID string, balance int64) error {
+func (f *fakeStore) GetPoolState(ctx context.Context, userID, poolID string) (PoolState, error) {
+	return PoolStateUnknown, nil
+}
+func (f *fakeStore) UpdatePoolState(ctx context.Context, userID, poolID string, state PoolState) error {
 	return nil
 }
-func (f *fakeStore) GetDelegationBalance(ctx context.Context, userID, delegationID string) (int64, error) {
-	return 0, nil
+func (f *fakeStore) GetDelegationBalance(ctx context.Context, userID, delegationID string) (Amount, error) {
+	return Amount{}, nil
 }
-func (f *fakeStore) UpdateDelegationBalance(ctx context.Context, userID, delegationID string, balance int64) error {
+func (f *fakeStore) UpdateDelegationBalance(ctx context.Context, userID, delegationID string, balance Amount) error {
 	return nil
 }
 func (f *fakeStore) AddNotification(ctx context.Context, userID string, chatID int64) error {
@@ -73,39 +205,160 @@ func (f *fakeStore) GetAllNotifications(ctx context.Context) ([]Notification, er
 }
 
 type fakeBalanceClient struct {
-	poolBalances       map[string]int64
-	delegationBalances map[string]int64
+	poolBalances       map[string]Amount
+	delegationBalances map[string]Amount
+	poolStates         map[string]PoolState
+	poolErrors         map[string]error
+	poolInfos          map[string]PoolInfo
+	delegationInfos    map[string]DelegationInfo
+	poolDelegations    map[string][]DelegationInfo
+	addressDelegations map[string][]DelegationInfo
+	addressPools       map[string][]PoolInfo
+	// serverHeight is the tip reported with pool and delegation info, 0
+	// for unknown.
+	serverHeight    int64
+	totalStake      Amount
+	addressBalances map[string]Amount
+	addressTokens   map[string]map[string]Amount
+	addressHistory  map[string][]TransactionInfo
+	tokenInfos      map[string]TokenInfo
+	tipHeight       int64
+	blocks          map[int64]BlockInfo
 }
 
-func (f *fakeBalanceClient) GetPoolBalance(poolID string) (int64, error) {
+func (f *fakeBalanceClient) GetTipHeight(ctx context.Context) (int64, error) {
+	return f.tipHeight, nil
+}
+
+func (f *fakeBalanceClient) GetBlock(ctx context.Context, height int64) (BlockInfo, error) {
+	block, ok := f.blocks[height]
+	if !ok {
+		return BlockInfo{}, errors.New("missing block")
+	}
+	return block, nil
+}
+
+func (f *fakeBalanceClient) GetPoolStatus(ctx context.Context, poolID string) (PoolStatus, error) {
+	if err, ok := f.poolErrors[poolID]; ok {
+		return PoolStatus{}, err
+	}
+	if state, ok := f.poolStates[poolID]; ok {
+		return PoolStatus{State: state}, nil
+	}
 	bal, ok := f.poolBalances[poolID]
 	if !ok {
-		return 0, errors.New("missing pool balance")
+		return PoolStatus{}, errors.New("missing pool balance")
 	}
-	return bal, nil
+	return PoolStatus{State: PoolStateActive, Balance: bal}, nil
+}
+
+func (f *fakeBalanceClient) GetPoolInfo(ctx context.Context, poolID string) (PoolInfo, error) {
+	if info, ok := f.poolInfos[poolID]; ok {
+		info.Height = f.serverHeight
+		return info, nil
+	}
+	status, err := f.GetPoolStatus(ctx, poolID)
+	if err != nil {
+		return PoolInfo{}, err
+	}
+	return PoolInfo{PoolID: poolID, State: status.State, StakerBalance: status.Balance, Height: f.serverHeight}, nil
 }
 
-func (f *fakeBalanceClient) GetDelegationBalance(delegationID string) (int64, error) {
+func (f *fakeBalanceClient) GetPoolDelegations(ctx context.Context, poolID string) ([]DelegationInfo, error) {
+	delegations, ok := f.poolDelegations[poolID]
+	if !ok {
+		return nil, &APIError{Kind: ErrNotFound, StatusCode: 404}
+	}
+	return delegations, nil
+}
+
+func (f *fakeBalanceClient) GetDelegationBalance(ctx context.Context, delegationID string) (Amount, error) {
 	bal, ok := f.delegationBalances[delegationID]
 	if !ok {
-		return 0, errors.New("missing delegation balance")
+		return Amount{}, errors.New("missing delegation balance")
 	}
 	return bal, nil
 }
 
+func (f *fakeBalanceClient) GetDelegationInfo(ctx context.Context, delegationID string) (DelegationInfo, error) {
+	if info, ok := f.delegationInfos[delegationID]; ok {
+		info.Height = f.serverHeight
+		return info, nil
+	}
+	bal, err := f.GetDelegationBalance(ctx, delegationID)
+	if err != nil {
+		return DelegationInfo{}, err
+	}
+	return DelegationInfo{DelegationID: delegationID, Balance: bal, Height: f.serverHeight}, nil
+}
+
+func (f *fakeBalanceClient) GetAddressBalance(ctx context.Context, address string) (AddressBalance, error) {
+	bal, ok := f.addressBalances[address]
+	if !ok {
+		return AddressBalance{}, errors.New("missing address balance")
+	}
+	tokens := make(map[string]Amount)
+	for tokenID, amount := range f.addressTokens[address] {
+		tokens[tokenID] = amount
+	}
+	var txIDs []string
+	for _, tx := range f.addressHistory[address] {
+		txIDs = append(txIDs, tx.TxID)
+	}
+	return AddressBalance{Coins: bal, Tokens: tokens, TransactionIDs: txIDs}, nil
+}
+
+func (f *fakeBalanceClient) GetAddressHistory(ctx context.Context, address string, offset, count int) (AddressHistory, error) {
+	txs := f.addressHistory[address]
+	start := min(offset, len(txs))
+	return AddressHistory{Total: len(txs), Transactions: txs[start:min(start+count, len(txs))]}, nil
+}
+
+func (f *fakeBalanceClient) GetAddressTransactions(ctx context.Context, address string, txIDs []string) ([]TransactionInfo, error) {
+	var txs []TransactionInfo
+	for _, txID := range txIDs {
+		for _, tx := range f.addressHistory[address] {
+			if tx.TxID == txID {
+				txs = append(txs, tx)
+			}
+		}
+	}
+	return txs, nil
+}
+
+func (f *fakeBalanceClient) GetAddressDelegations(ctx context.Context, address string) ([]DelegationInfo, error) {
+	return f.addressDelegations[address], nil
+}
+
+func (f *fakeBalanceClient) GetAddressPools(ctx context.Context, address string) ([]PoolInfo, error) {
+	return f.addressPools[address], nil
+}
+
+func (f *fakeBalanceClient) GetTotalStake(ctx context.Context, poolID string) (Amount, error) {
+	return f.totalStake, nil
+}
+
+func (f *fakeBalanceClient) GetTokenInfo(ctx context.Context, tokenID string) (TokenInfo, error) {
+	info, ok := f.tokenInfos[tokenID]
+	if !ok {
+		return TokenInfo{}, errors.New("missing token info")
+	}
+	return info, nil
+}
+
 func TestBalanceHandlerAggregatesBalances(t *testing.T) {
 	store := &fakeStore{
 		pools:       []string{"p1", "p2"},
 		delegations: []string{"d1", "d2"},
 	}
 	client := &fakeBalanceClient{
-		poolBalances: map[string]int64{
-			"p1": 2,
-			"p2": 3,
+		poolBalances: map[string]Amount{
+			"p1": AmountFromML(2),
+			"p2": AmountFromML(3),
 		},
-		delegationBalances: map[string]int64{
-			"d1": 1,
-			"d2": 2,
+		delegationBalances: map[string]Amount{
+			"d1": AmountFromML(1),
+			"d2": AmountFromML(2),
 		},
 	}
 	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
@@ -129,3 +382,129 @@ func TestBalanceHandlerAggregatesBalances(t *testing.T) {
 		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
 	}
 }
+
+func TestBalanceHandlerKeepsFractionalML(t *testing.T) {
+	store := &fakeStore{
+		pools:       []string{"p1"},
+		delegations: []string{"d1"},
+	}
+	client := &fakeBalanceClient{
+		poolBalances: map[string]Amount{
+			"p1": AmountFromAtoms(1_500_000_000_000_000),
+		},
+		delegationBalances: map[string]Amount{
+			"d1": AmountFromAtoms(25_000_000_000),
+		},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.balanceHandler(context.Background(), nil, update)
+	expected := "`1` pools: `15,000 ML`\n`1` delegations: `0.25 ML`\nTotal: `15,000.25 ML`"
+	if lastMessage != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+}
+
+func TestListPoolHandlerReportsLookupErrors(t *testing.T) {
+	store := &fakeStore{pools: []string{"p1", "p2", "p3", "p4"}}
+	client := &fakeBalanceClient{
+		poolBalances: map[string]Amount{"p1": AmountFromML(10)},
+		poolStates: map[string]PoolState{
+			"p2": PoolStateNotFound,
+			"p4": PoolStateDecommissioned,
+		},
+		poolErrors: map[string]error{
+			"p3": &APIError{Kind: ErrServer, URL: "p3", StatusCode: 500},
+		},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.listPoolHandler(context.Background(), nil, update)
+	expected := "Your pools:\n`p1`: 10 ML \n`p2`: `not found` \n`p3`: `unavailable` \n`p4`: `decommissioned` \n"
+	if lastMessage != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+}
+
+func TestBalanceHandlerRateLimited(t *testing.T) {
+	store := &fakeStore{pools: []string{"p1"}}
+	client := &fakeBalanceClient{
+		poolErrors: map[string]error{"p1": &APIError{Kind: ErrRateLimited, URL: "p1", StatusCode: 429}},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.balanceHandler(context.Background(), nil, update)
+	if lastMessage != "The Mintlayer API is rate limiting requests. Please try again in a minute." {
+		t.Fatalf("unexpected message: %q", lastMessage)
+	}
+}
+
+func TestBalanceHandlerIncludesAddresses(t *testing.T) {
+	store := &fakeStore{
+		pools:     []string{"p1"},
+		addresses: []MonitoredAddress{{Address: "a1"}, {Address: "a2"}},
+	}
+	client := &fakeBalanceClient{
+		poolBalances:    map[string]Amount{"p1": AmountFromML(10)},
+		addressBalances: map[string]Amount{"a1": AmountFromML(1), "a2": AmountFromAtoms(50_000_000_000)},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.balanceHandler(context.Background(), nil, update)
+	expected := "`1` pools: `10 ML`\n`0` delegations: `0 ML`\n`2` addresses: `1.5 ML`\nTotal: `11.5 ML`"
+	if lastMessage != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"slices"
+)
+
+// Kinds of entities whose balances are kept as observations.
+const (
+	observedPool       = "pool"
+	observedDelegation = "delegation"
+)
+
+// BalanceObservation is the balance of a pool or delegation read while the
+// chain tip was at Height. BlockID is the tip block then, empty if it was not
+// known. Announced is set once the balance was reported to the user.
+type BalanceObservation struct {
+	Height    int64
+	BlockID   string
+	Balance   Amount
+	Announced bool
+}
+
+// observedHeight is the height to record a balance of id at: the tip the
+// answering API server reported, or without one the local tip of id's
+// network. primaryHeight is the primary network's height seen at the start
+// of the check.
+func (a *App) observedHeight(ctx context.Context, id string, serverHeight, primaryHeight int64) int64 {
+	if serverHeight > 0 {
+		return serverHeight
+	}
+	return a.heightFor(ctx, id, primaryHeight)
+}
+
+// settledBalance records balance, read for a pool or delegation when the tip
+// was at height, and returns the observation to report: the newest one with
+// at least balanceConfirmations blocks on top whose block is still on the
+// main chain. It returns false when there is none yet, or when the balance
+// was read below the last announced height, e.g. from a lagging server.
+func (a *App) settledBalance(ctx context.Context, userID, kind, id string, balance Amount, height int64) (BalanceObservation, bool) {
+	if height <= 0 {
+		// Without a known tip there is nothing to order the reads by.
+		return BalanceObservation{Balance: balance}, true
+	}
+	observations, err := a.store.GetBalanceObservations(ctx, userID, kind, id)
+	if err != nil {
+		log.Printf("Error fetching balance observations of %s: %v", id, err)
+		return BalanceObservation{}, false
+	}
+	for _, observation := range observations {
+		if observation.Announced && observation.Height > height {
+			return BalanceObservation{}, false
+		}
+	}
+
+	tip := a.tipFor(ctx, id)
+	current := BalanceObservation{Height: height, Balance: balance}
+	if tip != nil {
+		if block, ok := tip.BlockAt(height); ok {
+			current.BlockID = block.ID
+		}
+	}
+	if err := a.store.AddBalanceObservation(ctx, userID, kind, id, current); err != nil {
+		log.Printf("Error recording balance of %s: %v", id, err)
+		return BalanceObservation{}, false
+	}
+	i, found := slices.BinarySearchFunc(observations, height, func(o BalanceObservation, h int64) int {
+		return cmp.Compare(o.Height, h)
+	})
+	if found {
+		current.Announced = observations[i].Announced
+		observations[i] = current
+	} else {
+		observations = slices.Insert(observations, i, current)
+	}
+
+	settledHeight := height - a.balanceConfirmations
+	for i := len(observations) - 1; i >= 0; i-- {
+		observation := observations[i]
+		if observation.Height > settledHeight {
+			continue
+		}
+		if tip != nil && observation.BlockID != "" {
+			if block, ok := tip.BlockAt(observation.Height); ok && block.ID != observation.BlockID {
+				// Read on a block that was reorganised away.
+				if err := a.store.RemoveBalanceObservation(ctx, userID, kind, id, observation.Height); err != nil {
+					log.Printf("Error removing balance observation of %s: %v", id, err)
+				}
+				continue
+			}
+		}
+		if !observation.Announced {
+			if err := a.store.SettleBalanceObservation(ctx, userID, kind, id, observation.Height); err != nil {
+				log.Printf("Error settling balance of %s: %v", id, err)
+				return BalanceObservation{}, false
+			}
+		}
+		return observation, true
+	}
+	return BalanceObservation{}, false
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+func TestBalanceChangesWaitForConfirmations(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO delegations (userID, delegationID, balance_atoms) VALUES ('u1', 'd1', '0')")
+	ctx := context.Background()
+
+	client := &fakeBalanceClient{
+		delegationBalances: map[string]Amount{"d1": AmountFromML(5)},
+		blocks:             map[int64]BlockInfo{},
+	}
+	for height := int64(1); height < 100; height++ {
+		client.blocks[height] = BlockInfo{Height: height, ID: fmt.Sprintf("b%d", height)}
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.tip = NewChainTipWatcher(client, time.Hour)
+	app.balanceConfirmations = 2
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	// cycle moves the tip to height and checks the delegation there.
+	cycle := func(height int64) []string {
+		t.Helper()
+		if _, ok := client.blocks[height]; !ok {
+			client.blocks[height] = BlockInfo{Height: height, ID: fmt.Sprintf("b%d", height)}
+		}
+		client.tipHeight = height
+		app.tip.poll(ctx)
+		messages = nil
+		app.notifyDelegationsBalanceChanges(ctx, "u1", 1, height)
+		return messages
+	}
+
+	if got := cycle(100); len(got) != 0 {
+		t.Fatalf("expected no announcement before confirmations, got %q", got)
+	}
+	if got := cycle(101); len(got) != 0 {
+		t.Fatalf("expected no announcement before confirmations, got %q", got)
+	}
+	if got := cycle(102); len(got) != 1 || got[0] != "`d1`: \\+5 ML at block `100`" {
+		t.Fatalf("unexpected announcement %q", got)
+	}
+
+	// A balance read on block 103 that is then reorganised away is never
+	// announced, and neither is its reversal.
+	client.delegationBalances["d1"] = AmountFromML(9)
+	if got := cycle(103); len(got) != 0 {
+		t.Fatalf("expected no announcement before confirmations, got %q", got)
+	}
+	client.blocks[103] = BlockInfo{Height: 103, ID: "b103-reorg"}
+	client.delegationBalances["d1"] = AmountFromML(5)
+	for height := int64(104); height <= 106; height++ {
+		if got := cycle(height); len(got) != 0 {
+			t.Fatalf("expected the reorganised balance not to be announced at %d, got %q", height, got)
+		}
+	}
+
+	// A lagging server answering for an older tip is ignored, even though
+	// the local tip has moved on.
+	client.delegationBalances["d1"] = AmountFromML(1)
+	client.serverHeight = 103
+	messages = nil
+	app.notifyDelegationsBalanceChanges(ctx, "u1", 1, 106)
+	client.serverHeight = 0
+	if len(messages) != 0 {
+		t.Fatalf("expected the stale balance to be ignored, got %q", messages)
+	}
+	observations, err := store.GetBalanceObservations(ctx, "u1", observedDelegation, "d1")
+	if err != nil {
+		t.Fatalf("GetBalanceObservations failed: %v", err)
+	}
+	for _, observation := range observations {
+		if observation.Height < 104 {
+			t.Fatalf("expected observations below the announced height to be gone, got %+v", observations)
+		}
+	}
+
+	if err := store.RemoveDelegation(ctx, "u1", "d1"); err != nil {
+		t.Fatalf("RemoveDelegation failed: %v", err)
+	}
+	if observations, _ := store.GetBalanceObservations(ctx, "u1", observedDelegation, "d1"); len(observations) != 0 {
+		t.Fatalf("expected the observations to be removed with the delegation, got %+v", observations)
+	}
+}
+
+func TestReorganisedDecommissionIsNotAnnounced(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO pools (userID, poolID, balance_atoms, state) VALUES ('u1', 'p1', ?, 'active')", AmountFromML(50000).String())
+	ctx := context.Background()
+
+	client := &fakeBalanceClient{
+		poolStates: map[string]PoolState{"p1": PoolStateDecommissioned},
+		blocks:     map[int64]BlockInfo{},
+	}
+	for height := int64(1); height < 100; height++ {
+		client.blocks[height] = BlockInfo{Height: height, ID: fmt.Sprintf("b%d", height)}
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.tip = NewChainTipWatcher(client, time.Hour)
+	app.balanceConfirmations = 2
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	cycle := func(height int64) {
+		t.Helper()
+		if _, ok := client.blocks[height]; !ok {
+			client.blocks[height] = BlockInfo{Height: height, ID: fmt.Sprintf("b%d", height)}
+		}
+		client.tipHeight = height
+		app.tip.poll(ctx)
+		app.notifyPoolsBalanceChanges(ctx, "u1", 1, height)
+	}
+
+	// The decommission seen on block 100 is reorganised away before it is
+	// confirmed.
+	cycle(100)
+	client.blocks[100] = BlockInfo{Height: 100, ID: "b100-reorg"}
+	delete(client.poolStates, "p1")
+	client.poolBalances = map[string]Amount{"p1": AmountFromML(50000)}
+	for height := int64(101); height <= 103; height++ {
+		cycle(height)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("expected the reorganised decommission not to be announced, got %q", messages)
+	}
+	if state, _ := store.GetPoolState(ctx, "u1", "p1"); state != PoolStateActive {
+		t.Fatalf("expected the pool to stay active, got %v", state)
+	}
+
+	// A decommission that stays is announced once confirmed.
+	client.poolStates = map[string]PoolState{"p1": PoolStateDecommissioned}
+	for height := int64(104); height <= 107; height++ {
+		cycle(height)
+	}
+	if len(messages) != 1 || messages[0] != "`p1`: `decommissioned` (\\-50,000 ML) at block `104`" {
+		t.Fatalf("unexpected announcements %q", messages)
+	}
+	if state, _ := store.GetPoolState(ctx, "u1", "p1"); state != PoolStateDecommissioned {
+		t.Fatalf("expected decommissioned state, got %v", state)
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+import "time"
+
+// BlockInfo is what the bot needs from a block: who produced it, when, and
+// what it paid out. PoolID is empty for blocks not produced by a stake pool.
+// Reward is zero when the block does not state it, see parseBlockInfo.
+type BlockInfo struct {
+	Height    int64
+	ID        string
+	Timestamp time.Time
+	PoolID    string
+	Reward    Amount
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTipPollInterval  = 30 * time.Second
+	defaultMaxCheckInterval = 10 * time.Minute
+	// recentBlocksLimit is how many of the latest blocks are kept for block
+	// production notifications, about an hour of chain time.
+	recentBlocksLimit = 30
+	// blockCatchUpLimit caps how many blocks older than the kept ones are
+	// fetched to catch up after downtime, about a day of chain time.
+	blockCatchUpLimit = 720
+)
+
+// ChainTipWatcher polls the chain tip height and wakes up subscribers when a
+// new block arrives, so balance re-checks follow chain activity instead of a
+// fixed timer. It also keeps the latest blocks so every user's routine can
+// look at them without fetching them again.
+type ChainTipWatcher struct {
+	client       BalanceClient
+	pollInterval time.Duration
+
+	mu      sync.Mutex
+	height  int64
+	seenAt  time.Time
+	changed chan struct{}
+	blocks  []BlockInfo
+}
+
+func NewChainTipWatcher(client BalanceClient, pollInterval time.Duration) *ChainTipWatcher {
+	if pollInterval <= 0 {
+		pollInterval = defaultTipPollInterval
+	}
+	return &ChainTipWatcher{
+		client:       client,
+		pollInterval: pollInterval,
+		changed:      make(chan struct{}),
+	}
+}
+
+// Tip returns the last seen tip height (0 before the first successful poll)
+// and a channel that is closed once a higher tip is seen.
+func (w *ChainTipWatcher) Tip() (int64, <-chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.height, w.changed
+}
+
+// SeenAt returns when the current tip height was first seen, the zero time
+// before the first successful poll.
+func (w *ChainTipWatcher) SeenAt() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seenAt
+}
+
+// Run polls the tip until ctx is cancelled. Failed polls keep the last known
+// height; subscribers then fall back to their maximum check interval.
+func (w *ChainTipWatcher) Run(ctx context.Context) {
+	ctx = withBackgroundPriority(ctx)
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	for {
+		w.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *ChainTipWatcher) poll(ctx context.Context) {
+	height, err := w.client.GetTipHeight(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error fetching chain tip: %v", err)
+		}
+		return
+	}
+	w.fetchBlocks(ctx, height)
+	w.advance(height)
+}
+
+// fetchBlocks loads the blocks up to height that are not known yet. On a
+// failure the rest is fetched on the next poll.
+func (w *ChainTipWatcher) fetchBlocks(ctx context.Context, height int64) {
+	w.dropReorganisedBlocks(ctx)
+	w.mu.Lock()
+	from := height - recentBlocksLimit + 1
+	if n := len(w.blocks); n > 0 && w.blocks[n-1].Height+1 > from {
+		from = w.blocks[n-1].Height + 1
+	}
+	w.mu.Unlock()
+	if from < 1 {
+		from = 1
+	}
+
+	for h := from; h <= height; h++ {
+		block, err := w.client.GetBlock(ctx, h)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Error fetching block %d: %v", h, err)
+			}
+			return
+		}
+		w.addBlock(block)
+	}
+}
+
+// dropReorganisedBlocks forgets the newest known blocks that are no longer on
+// the main chain, so they are fetched again. It stops at the first block
+// that is unchanged or cannot be fetched.
+func (w *ChainTipWatcher) dropReorganisedBlocks(ctx context.Context) {
+	for {
+		known, ok := w.LatestBlock()
+		if !ok {
+			return
+		}
+		block, err := w.client.GetBlock(ctx, known.Height)
+		if err != nil || block.ID == known.ID {
+			return
+		}
+		log.Printf("Block %d was reorganised: %s replaced by %s", known.Height, known.ID, block.ID)
+		w.mu.Lock()
+		if n := len(w.blocks); n > 0 && w.blocks[n-1].ID == known.ID {
+			w.blocks = w.blocks[:n-1]
+		}
+		w.mu.Unlock()
+	}
+}
+
+func (w *ChainTipWatcher) addBlock(block BlockInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.blocks = append(w.blocks, block)
+	if len(w.blocks) > recentBlocksLimit {
+		w.blocks = append([]BlockInfo(nil), w.blocks[len(w.blocks)-recentBlocksLimit:]...)
+	}
+}
+
+// LatestBlock returns the newest known block. Its timestamp serves as the
+// chain's clock, which stops when the API server cannot be reached.
+func (w *ChainTipWatcher) LatestBlock() (BlockInfo, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.blocks) == 0 {
+		return BlockInfo{}, false
+	}
+	return w.blocks[len(w.blocks)-1], true
+}
+
+// BlockAt returns the known block at height, if it is among the latest
+// blocks.
+func (w *ChainTipWatcher) BlockAt(height int64) (BlockInfo, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, block := range w.blocks {
+		if block.Height == height {
+			return block, true
+		}
+	}
+	return BlockInfo{}, false
+}
+
+// BlocksAfter returns the known blocks above height, oldest first.
+func (w *ChainTipWatcher) BlocksAfter(height int64) []BlockInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var blocks []BlockInfo
+	for _, block := range w.blocks {
+		if block.Height > height {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// BlocksSince returns the blocks above height, oldest first, like
+// BlocksAfter, but also fetches the ones older than the kept latest blocks,
+// e.g. after the bot was down. At most blockCatchUpLimit of them are fetched
+// and older ones are skipped. On a failed fetch the blocks up to it are
+// returned with the error.
+func (w *ChainTipWatcher) BlocksSince(ctx context.Context, height int64) ([]BlockInfo, error) {
+	kept := w.BlocksAfter(height)
+	if height <= 0 || len(kept) == 0 || kept[0].Height <= height+1 {
+		return kept, nil
+	}
+	from, to := height+1, kept[0].Height-1
+	if to-from+1 > blockCatchUpLimit {
+		log.Printf("Skipping blocks %d to %d, more than %d blocks behind", from, to-blockCatchUpLimit, blockCatchUpLimit)
+		from = to - blockCatchUpLimit + 1
+	}
+	var blocks []BlockInfo
+	for h := from; h <= to; h++ {
+		block, err := w.client.GetBlock(ctx, h)
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, block)
+	}
+	return append(blocks, kept...), nil
+}
+
+func (w *ChainTipWatcher) advance(height int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if height <= w.height {
+		return
+	}
+	w.height = height
+	w.seenAt = time.Now()
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
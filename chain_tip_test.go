@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+func TestChainTipWatcherWakesOnNewBlock(t *testing.T) {
+	client := &fakeBalanceClient{tipHeight: 100}
+	watcher := NewChainTipWatcher(client, time.Hour)
+
+	watcher.poll(context.Background())
+	height, changed := watcher.Tip()
+	if height != 100 {
+		t.Fatalf("expected height 100, got %d", height)
+	}
+
+	watcher.advance(100)
+	select {
+	case <-changed:
+		t.Fatal("expected no wake-up without a new block")
+	default:
+	}
+
+	watcher.advance(101)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("expected wake-up on a new block")
+	}
+	if height, _ := watcher.Tip(); height != 101 {
+		t.Fatalf("expected height 101, got %d", height)
+	}
+}
+
+func TestNotifyRoutineRechecksOnNewBlock(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO delegations (userID, delegationID, balance_atoms) VALUES ('u1', 'd1', '0')")
+
+	client := &fakeBalanceClient{delegationBalances: map[string]Amount{"d1": AmountFromML(5)}}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+	app.maxCheckInterval = time.Hour
+	app.tip = NewChainTipWatcher(client, time.Hour)
+	app.tip.advance(100)
+
+	messages := make(chan string, 10)
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages <- message
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.notifyBalanceChangesRoutine(ctx, "u1", 1)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	expectMessage := func(expected string) {
+		t.Helper()
+		select {
+		case message := <-messages:
+			if message != expected {
+				t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, message)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %q", expected)
+		}
+	}
+
+	expectMessage("`d1`: \\+5 ML at block `100`")
+	client.delegationBalances["d1"] = AmountFromML(6)
+	app.tip.advance(101)
+	expectMessage("`d1`: \\+1 ML at block `101`")
+}
+
+func TestChainTipWatcherKeepsRecentBlocks(t *testing.T) {
+	client := &fakeBalanceClient{tipHeight: 100, blocks: map[int64]BlockInfo{}}
+	for h := int64(1); h <= 101; h++ {
+		client.blocks[h] = BlockInfo{Height: h, PoolID: "p1"}
+	}
+	watcher := NewChainTipWatcher(client, time.Hour)
+
+	watcher.poll(context.Background())
+	blocks := watcher.BlocksAfter(0)
+	if len(blocks) != recentBlocksLimit || blocks[0].Height != 100-recentBlocksLimit+1 || blocks[len(blocks)-1].Height != 100 {
+		t.Fatalf("unexpected recent blocks: %d, first %d", len(blocks), blocks[0].Height)
+	}
+
+	client.tipHeight = 101
+	watcher.poll(context.Background())
+	if blocks := watcher.BlocksAfter(99); len(blocks) != 2 || blocks[1].Height != 101 {
+		t.Fatalf("expected blocks 100 and 101, got %+v", blocks)
+	}
+}
+
+func TestNotifyPoolBlocksAnnouncesOnce(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO pools (userID, poolID) VALUES ('u1', 'p1')")
+	exec("INSERT INTO pools (userID, poolID) VALUES ('u1', 'p2')")
+	ctx := context.Background()
+	if err := store.SetPoolBlockNotifications(ctx, "u1", "p1", true, 100); err != nil {
+		t.Fatalf("SetPoolBlockNotifications failed: %v", err)
+	}
+	if err := store.SetPoolBlockNotifications(ctx, "u1", "p9", true, 100); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for an untracked pool, got %v", err)
+	}
+
+	client := &fakeBalanceClient{}
+	newApp := func() (*App, *[]string) {
+		app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+		app.tip = NewChainTipWatcher(client, time.Hour)
+		at := time.Date(2026, 10, 17, 12, 30, 0, 0, time.UTC)
+		app.tip.addBlock(BlockInfo{Height: 100, PoolID: "p1", Timestamp: at})
+		app.tip.addBlock(BlockInfo{Height: 101, PoolID: "p2", Timestamp: at})
+		app.tip.addBlock(BlockInfo{Height: 102, PoolID: "p1", Timestamp: at, Reward: AmountFromML(202)})
+		var messages []string
+		app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+			messages = append(messages, message)
+			return nil
+		}
+		return app, &messages
+	}
+
+	app, messages := newApp()
+	app.notifyPoolBlocks(ctx, "u1", 1)
+	expected := "`p1`: produced block `102` at 2026-10-17 12:30:00 UTC, reward 202 ML"
+	if len(*messages) != 1 || (*messages)[0] != expected {
+		t.Fatalf("expected only the new p1 block, got %v", *messages)
+	}
+
+	// A restart starts from an empty watcher state but the stored height.
+	app, messages = newApp()
+	app.notifyPoolBlocks(ctx, "u1", 1)
+	if len(*messages) != 0 {
+		t.Fatalf("expected no repeated announcements, got %v", *messages)
+	}
+}
+
+func TestNotifyPoolBlocksCatchesUpAfterDowntime(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO pools (userID, poolID) VALUES ('u1', 'p1')")
+	ctx := context.Background()
+	// Blocks were last announced two hours of chain time before the tip.
+	if err := store.SetPoolBlockNotifications(ctx, "u1", "p1", true, 40); err != nil {
+		t.Fatalf("SetPoolBlockNotifications failed: %v", err)
+	}
+
+	client := &fakeBalanceClient{tipHeight: 100, blocks: map[int64]BlockInfo{}}
+	for h := int64(1); h <= 100; h++ {
+		client.blocks[h] = BlockInfo{Height: h, PoolID: "p2"}
+	}
+	client.blocks[45] = BlockInfo{Height: 45, PoolID: "p1"}
+	client.blocks[99] = BlockInfo{Height: 99, PoolID: "p1"}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.tip = NewChainTipWatcher(client, time.Hour)
+	app.tip.poll(ctx)
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.notifyPoolBlocks(ctx, "u1", 1)
+	if len(messages) != 2 || !strings.Contains(messages[0], "block `45`") || !strings.Contains(messages[1], "block `99`") {
+		t.Fatalf("expected blocks 45 and 99, got %q", messages)
+	}
+	if strings.Contains(messages[0], "reward") {
+		t.Fatalf("expected no reward for a block that does not state it, got %q", messages[0])
+	}
+	if pools, _ := store.GetBlockNotificationPools(ctx, "u1"); pools["p1"] != 100 {
+		t.Fatalf("expected the scanned height to be stored, got %v", pools)
+	}
+
+	messages = nil
+	app.notifyPoolBlocks(ctx, "u1", 1)
+	if len(messages) != 0 {
+		t.Fatalf("expected no repeated announcements, got %q", messages)
+	}
+}
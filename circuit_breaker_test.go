@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerLifecycle(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker(3, time.Minute)
+	breaker.now = func() time.Time { return now }
+	serverErr := &APIError{Kind: ErrServer, StatusCode: 503}
+
+	for i := 0; i < 3; i++ {
+		if err := breaker.allow(); err != nil {
+			t.Fatalf("call %d refused while closed: %v", i, err)
+		}
+		breaker.record(serverErr)
+	}
+	if !breaker.Open() || !errors.Is(breaker.allow(), ErrCircuitOpen) {
+		t.Fatalf("expected the breaker to open, state %v", breaker.State())
+	}
+
+	now = now.Add(time.Minute)
+	if breaker.Open() {
+		t.Fatal("expected a probe to be possible after the cooldown")
+	}
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	if !errors.Is(breaker.allow(), ErrCircuitOpen) {
+		t.Fatal("expected only one probe while half-open")
+	}
+	breaker.record(serverErr)
+	if breaker.State() != breakerOpen {
+		t.Fatalf("expected a failed probe to reopen, got %v", breaker.State())
+	}
+
+	now = now.Add(time.Minute)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("second probe refused: %v", err)
+	}
+	breaker.record(&APIError{Kind: ErrNotFound, StatusCode: 404})
+	if breaker.State() != breakerClosed || breaker.Open() {
+		t.Fatalf("expected a successful probe to close, got %v", breaker.State())
+	}
+}
+
+func TestHTTPBalanceClientFailsFastWhenCircuitOpen(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewHTTPBalanceClient(server.URL)
+	client.SetCircuitBreaker(2, time.Hour)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetPoolStatus(context.Background(), "mpool1test"); !errors.Is(err, ErrServer) {
+			t.Fatalf("expected a server error, got %v", err)
+		}
+	}
+
+	before := calls.Load()
+	_, err := client.GetPoolStatus(context.Background(), "mpool1test")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls.Load() != before {
+		t.Fatalf("expected no request while open, got %d more calls", calls.Load()-before)
+	}
+
+	app := NewApp(&fakeStore{}, client, nil, NewNotificationManager(), "", context.Background())
+	if !app.apiCircuitOpen() {
+		t.Fatal("expected the app to see the open circuit")
+	}
+}
@@ -0,0 +1,59 @@
+// Command fakeapi runs the fake Mintlayer API server for local development.
+// Point the bot's api_base_url at it and change balances through the /fake/
+// control endpoints, e.g.
+//
+//	curl -X PUT localhost:3000/fake/state -d '{"pools":{"mpool1...":{"staker_balance":"4000000000000000"}}}'
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"mintlayer_bot/fakeapi"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:3000", "listen address")
+	scenario := flag.String("scenario", "", "JSON file with the initial state")
+	blockInterval := flag.Duration("block-interval", 0, "produce a block this often, rotating through the pools (0 disables)")
+	blockReward := flag.String("block-reward", "20200000000000", "reward of produced blocks in atoms")
+	flag.Parse()
+
+	api := fakeapi.New()
+	if *scenario != "" {
+		data, err := os.ReadFile(*scenario)
+		if err != nil {
+			log.Fatalf("Error reading scenario: %v", err)
+		}
+		var state fakeapi.State
+		if err := json.Unmarshal(data, &state); err != nil {
+			log.Fatalf("Error parsing scenario: %v", err)
+		}
+		api.Apply(state)
+	}
+	if *blockInterval > 0 {
+		go produceBlocks(api, *blockInterval, *blockReward)
+	}
+
+	log.Printf("Fake Mintlayer API listening on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, api))
+}
+
+func produceBlocks(api *fakeapi.API, interval time.Duration, reward string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		<-ticker.C
+		pools := api.PoolIDs()
+		if len(pools) == 0 {
+			continue
+		}
+		pool := pools[i%len(pools)]
+		height := api.AddBlock(fakeapi.Block{PoolID: pool, Reward: reward})
+		log.Printf("Block %d produced by %s", height, pool)
+	}
+}
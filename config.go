@@ -2,14 +2,66 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 )
 
 type Config struct {
-	BotToken   string `json:"bot_token"`
-	APIBaseURL string `json:"api_base_url"`
-	AdminUser  string `json:"admin_user"`
+	BotToken       string  `json:"bot_token"`
+	APIBaseURL     URLList `json:"api_base_url"`
+	AdminUser      string  `json:"admin_user"`
+	AmountDecimals *int    `json:"amount_decimals,omitempty"`
+	// TipPollSeconds is how often the chain tip height is polled.
+	TipPollSeconds int `json:"tip_poll_seconds,omitempty"`
+	// MaxCheckIntervalMinutes bounds the time between balance checks when
+	// no new block is seen.
+	MaxCheckIntervalMinutes int `json:"max_check_interval_minutes,omitempty"`
+	// BalanceConfirmations is how many blocks must follow a pool or
+	// delegation balance change before it is announced, so that changes
+	// undone by a reorganisation are not reported. 0 announces at once.
+	BalanceConfirmations int `json:"balance_confirmations,omitempty"`
+	// Networks adds custom networks or overrides the built-in mainnet and
+	// testnet, see NetworkConfig.
+	Networks []NetworkConfig `json:"networks,omitempty"`
+	// HealthCheckSeconds is how often every API server is health-checked.
+	HealthCheckSeconds int `json:"health_check_seconds,omitempty"`
+	// CircuitBreakerFailures consecutive server failures open the circuit
+	// breaker for CircuitBreakerCooldownSeconds.
+	CircuitBreakerFailures        int `json:"circuit_breaker_failures,omitempty"`
+	CircuitBreakerCooldownSeconds int `json:"circuit_breaker_cooldown_seconds,omitempty"`
+	// APIRequestsPerSecond and APIMaxInFlight cap all outbound API requests
+	// of the process. 0 keeps the default, a negative value disables the
+	// limit.
+	APIRequestsPerSecond float64 `json:"api_requests_per_second,omitempty"`
+	APIMaxInFlight       int     `json:"api_max_in_flight,omitempty"`
+	// CacheTTLSeconds is how long balance lookups are shared between users
+	// and commands. 0 keeps the default, a negative value disables the cache.
+	CacheTTLSeconds int `json:"cache_ttl_seconds,omitempty"`
+	// PriceProvider enables fiat values next to ML amounts for users who
+	// pick a currency. Without it fiat conversion is off.
+	PriceProvider *PriceProviderConfig `json:"price_provider,omitempty"`
+}
+
+// URLList is a list of API server URLs in order of preference. In JSON it is
+// either a single string or an array of strings.
+type URLList []string
+
+func (l *URLList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = nil
+		if single != "" {
+			*l = URLList{single}
+		}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("api_base_url must be a string or a list of strings")
+	}
+	*l = list
+	return nil
 }
 
 func readConfig(file string) (*Config, error) {
@@ -3,10 +3,8 @@ package main
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 
-	"github.com/btcsuite/btcd/btcutil/bech32"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -70,9 +68,179 @@ func initDB(filePath string) (*sql.DB, error) {
 		return nil, err
 	}
 
+	if err := migrateDB(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
 	return db, nil
 }
 
+// migrations are applied in order on top of the base schema created by
+// initDB. PRAGMA user_version records how many have already run.
+var migrations = [][]string{
+	// 1: keep balances in atoms. The legacy balance columns hold whole ML.
+	{
+		"ALTER TABLE pools ADD COLUMN balance_atoms TEXT NOT NULL DEFAULT '0'",
+		"UPDATE pools SET balance_atoms = CAST(balance AS TEXT) || '00000000000' WHERE balance IS NOT NULL AND balance != 0",
+		"ALTER TABLE delegations ADD COLUMN balance_atoms TEXT NOT NULL DEFAULT '0'",
+		"UPDATE delegations SET balance_atoms = CAST(balance AS TEXT) || '00000000000' WHERE balance IS NOT NULL AND balance != 0",
+		"ALTER TABLE addresses ADD COLUMN balance_atoms TEXT NOT NULL DEFAULT '0'",
+		"UPDATE addresses SET balance_atoms = CAST(balance AS TEXT) || '00000000000' WHERE balance IS NOT NULL AND balance != 0",
+	},
+	// 2: last seen pool lifecycle state, see PoolState.storageKey.
+	{
+		"ALTER TABLE pools ADD COLUMN state TEXT NOT NULL DEFAULT ''",
+	},
+	// 3: last notified fungible token balances of monitored addresses.
+	{
+		`CREATE TABLE IF NOT EXISTS address_tokens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			userID TEXT NOT NULL,
+			address TEXT NOT NULL,
+			tokenID TEXT NOT NULL,
+			balance_atoms TEXT NOT NULL DEFAULT '0',
+			UNIQUE(userID, address, tokenID) ON CONFLICT REPLACE
+		)`,
+	},
+	// 4: opt-in block production notifications and the last announced block.
+	{
+		"ALTER TABLE pools ADD COLUMN notify_blocks INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE pools ADD COLUMN last_block_height INTEGER NOT NULL DEFAULT 0",
+	},
+	// 5: pool liveness alerts. The window is in minutes (0 = off) and the
+	// last block time in unix seconds.
+	{
+		"ALTER TABLE pools ADD COLUMN liveness_window_minutes INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE pools ADD COLUMN last_block_time INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE pools ADD COLUMN liveness_alerted INTEGER NOT NULL DEFAULT 0",
+	},
+	// 6: the network each tracked entity belongs to. Everything tracked so
+	// far was validated as mainnet.
+	{
+		"ALTER TABLE pools ADD COLUMN network TEXT NOT NULL DEFAULT 'mainnet'",
+		"ALTER TABLE delegations ADD COLUMN network TEXT NOT NULL DEFAULT 'mainnet'",
+		"ALTER TABLE addresses ADD COLUMN network TEXT NOT NULL DEFAULT 'mainnet'",
+	},
+	// 7: balance samples of tracked pools for reward estimates, shared by
+	// all users. observed_at is in unix seconds.
+	{
+		`CREATE TABLE IF NOT EXISTS pool_samples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			poolID TEXT NOT NULL,
+			observed_at INTEGER NOT NULL,
+			height INTEGER NOT NULL DEFAULT 0,
+			staker_balance_atoms TEXT NOT NULL,
+			delegations_balance_atoms TEXT NOT NULL
+		)`,
+		"CREATE INDEX IF NOT EXISTS pool_samples_pool_time ON pool_samples (poolID, observed_at)",
+	},
+	// 8: per-user preferences. An empty currency turns fiat values off.
+	{
+		`CREATE TABLE IF NOT EXISTS user_settings (
+			userID TEXT PRIMARY KEY,
+			currency TEXT NOT NULL DEFAULT ''
+		)`,
+	},
+	// 9: alerts on new and withdrawn delegations of a tracked pool, with the
+	// delegations last seen for it.
+	{
+		"ALTER TABLE pools ADD COLUMN notify_delegations INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE pools ADD COLUMN delegation_alert_atoms TEXT NOT NULL DEFAULT '0'",
+		`CREATE TABLE IF NOT EXISTS pool_delegations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			userID TEXT NOT NULL,
+			poolID TEXT NOT NULL,
+			delegationID TEXT NOT NULL,
+			balance_atoms TEXT NOT NULL DEFAULT '0',
+			UNIQUE(userID, poolID, delegationID) ON CONFLICT REPLACE
+		)`,
+	},
+	// 10: addresses whose new delegations are tracked automatically.
+	{
+		`CREATE TABLE IF NOT EXISTS synced_addresses (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			userID TEXT NOT NULL,
+			address TEXT NOT NULL,
+			network TEXT NOT NULL,
+			UNIQUE(userID, address) ON CONFLICT IGNORE
+		)`,
+	},
+	// 11: the newest transaction of an address covered by a notification.
+	{
+		"ALTER TABLE addresses ADD COLUMN last_tx_id TEXT NOT NULL DEFAULT ''",
+	},
+	// 12: per-transaction transfer alerts, with the transactions already
+	// alerted so none is announced twice.
+	{
+		"ALTER TABLE addresses ADD COLUMN transfer_alerts INTEGER NOT NULL DEFAULT 0",
+		`CREATE TABLE IF NOT EXISTS address_transfers (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			userID TEXT NOT NULL,
+			address TEXT NOT NULL,
+			txID TEXT NOT NULL,
+			confirmed INTEGER NOT NULL DEFAULT 0,
+			UNIQUE(userID, address, txID)
+		)`,
+	},
+	// 13: pool and delegation balances keyed by the block they were read at.
+	// Observations are kept until a later one is announced.
+	{
+		`CREATE TABLE IF NOT EXISTS balance_observations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			userID TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			entityID TEXT NOT NULL,
+			height INTEGER NOT NULL,
+			block_id TEXT NOT NULL DEFAULT '',
+			balance_atoms TEXT NOT NULL,
+			announced INTEGER NOT NULL DEFAULT 0,
+			UNIQUE(userID, kind, entityID, height)
+		)`,
+	},
+	// 14: liveness alerts without a window expect blocks at the pool's stake
+	// share. liveness_height is the last block scanned for the pool.
+	{
+		"ALTER TABLE pools ADD COLUMN liveness_enabled INTEGER NOT NULL DEFAULT 0",
+		"UPDATE pools SET liveness_enabled = 1 WHERE liveness_window_minutes > 0",
+		"ALTER TABLE pools ADD COLUMN liveness_height INTEGER NOT NULL DEFAULT 0",
+	},
+	// 15: the whole-ML balance columns of the base schema were replaced by
+	// balance_atoms in migration 1.
+	{
+		"ALTER TABLE pools DROP COLUMN balance",
+		"ALTER TABLE delegations DROP COLUMN balance",
+		"ALTER TABLE addresses DROP COLUMN balance",
+	},
+}
+
+func migrateDB(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, stmt := range migrations[i] {
+			if _, err := tx.Exec(stmt); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migration %d: %w", i+1, err)
+			}
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func applyDBPragmas(db *sql.DB) error {
 	pragmas := []string{
 		"PRAGMA journal_mode=WAL",
@@ -92,6 +260,32 @@ type Notification struct {
 	ChatID int64
 }
 
+// MonitoredAddress is a row of the addresses table. With NotifyOnChange set
+// every balance change is announced; otherwise only changes of at least
+// Threshold whole ML since the last announcement are.
+type MonitoredAddress struct {
+	Address        string
+	Balance        Amount
+	NotifyOnChange bool
+	Threshold      int
+	// LastTxID is the newest transaction covered by a notification, empty
+	// until one is sent.
+	LastTxID string
+}
+
+// announces reports whether moving from the last announced balance to
+// balance should be notified.
+func (m MonitoredAddress) announces(balance Amount) bool {
+	delta := balance.Sub(m.Balance)
+	if delta.IsZero() {
+		return false
+	}
+	if m.NotifyOnChange {
+		return true
+	}
+	return delta.Abs().Cmp(AmountFromML(int64(m.Threshold))) >= 0
+}
+
 func getAllNotificationsWithContext(ctx context.Context, db *sql.DB) ([]Notification, error) {
 	rows, err := db.QueryContext(ctx, "SELECT userID, chatID FROM notifications")
 	if err != nil {
@@ -126,8 +320,8 @@ func removeMonitoredAddressWithContext(ctx context.Context, db *sql.DB, userID,
 	return err
 }
 
-func updateAddressBalanceWithContext(ctx context.Context, db *sql.DB, userID, address string, balance int64) error {
-	_, err := db.ExecContext(ctx, "UPDATE addresses SET balance = ? WHERE address = ? AND userID = ?", balance, address, userID)
+func updateAddressBalanceWithContext(ctx context.Context, db *sql.DB, userID, address string, balance Amount) error {
+	_, err := db.ExecContext(ctx, "UPDATE addresses SET balance_atoms = ? WHERE address = ? AND userID = ?", balance, address, userID)
 	return err
 }
 
@@ -141,34 +335,44 @@ func removeNotificationWithContext(ctx context.Context, db *sql.DB, userID strin
 	return err
 }
 
-func addDelegationWithContext(ctx context.Context, db *sql.DB, userID, delegationID string) error {
-	hrp, _, err := bech32.Decode(delegationID)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return err
-	}
-	if hrp != "mdelg" {
-		fmt.Println("Error: this is not a delegation mainnet address")
-		return errors.New("this is not a delegation mainnet address")
+// execer runs statements on a *sql.DB or inside a *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func addDelegationWithContext(ctx context.Context, db execer, userID, delegationID, network string) error {
+	if !isBech32m(delegationID) {
+		return fmt.Errorf("invalid delegation ID %q", delegationID)
 	}
 
-	_, err = db.ExecContext(ctx, "INSERT INTO delegations (userID, delegationID, balance) VALUES (?, ?, 0)", userID, delegationID)
+	_, err := db.ExecContext(ctx, "INSERT INTO delegations (userID, delegationID, network) VALUES (?, ?, ?)", userID, delegationID, network)
 	return err
 }
 
-func updateDelegationBalanceWithContext(ctx context.Context, db *sql.DB, userID, delegationID string, balance int64) error {
-	_, err := db.ExecContext(ctx, "UPDATE delegations SET balance = ? WHERE delegationID = ? AND userID = ?", balance, delegationID, userID)
+func updateDelegationBalanceWithContext(ctx context.Context, db *sql.DB, userID, delegationID string, balance Amount) error {
+	_, err := db.ExecContext(ctx, "UPDATE delegations SET balance_atoms = ? WHERE delegationID = ? AND userID = ?", balance, delegationID, userID)
 	return err
 }
 
 func removeDelegationWithContext(ctx context.Context, db *sql.DB, userID, delegationID string) error {
-	_, err := db.ExecContext(ctx, "DELETE FROM delegations WHERE userID = ? AND delegationID = ?", userID, delegationID)
-	return err
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM delegations WHERE userID = ? AND delegationID = ?", userID, delegationID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM balance_observations WHERE userID = ? AND kind = ? AND entityID = ?", userID, observedDelegation, delegationID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-func getDelegationBalanceFromDbWithContext(ctx context.Context, db *sql.DB, userID, delegationID string) (int64, error) {
-	var balance int64
-	err := db.QueryRowContext(ctx, "SELECT balance FROM delegations WHERE userID = ? AND delegationID = ?", userID, delegationID).Scan(&balance)
+func getDelegationBalanceFromDbWithContext(ctx context.Context, db *sql.DB, userID, delegationID string) (Amount, error) {
+	var balance Amount
+	err := db.QueryRowContext(ctx, "SELECT balance_atoms FROM delegations WHERE userID = ? AND delegationID = ?", userID, delegationID).Scan(&balance)
 	return balance, err
 }
 
@@ -190,34 +394,23 @@ func getDelegationsWithContext(ctx context.Context, db *sql.DB, userID string) (
 	return delegations, nil
 }
 
-func addPoolWithContext(ctx context.Context, db *sql.DB, userID, poolID string) error {
-	hrp, _, err := bech32.Decode(poolID)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return err
-	}
-	if hrp != "mpool" {
-		fmt.Println("Error: this is not a pool mainnet address")
-		return errors.New("this is not a pool mainnet address")
+func addPoolWithContext(ctx context.Context, db execer, userID, poolID, network string) error {
+	if !isBech32m(poolID) {
+		return fmt.Errorf("invalid pool ID %q", poolID)
 	}
 
-	_, err = db.ExecContext(ctx, "INSERT INTO pools (userID, poolID, balance) VALUES (?, ?, 0)", userID, poolID)
-	return err
-}
-
-func updatePoolBalanceWithContext(ctx context.Context, db *sql.DB, userID, poolID string, balance int64) error {
-	_, err := db.ExecContext(ctx, "UPDATE pools SET balance = ? WHERE poolID = ? AND userID = ?", balance, poolID, userID)
+	_, err := db.ExecContext(ctx, "INSERT INTO pools (userID, poolID, network) VALUES (?, ?, ?)", userID, poolID, network)
 	return err
 }
 
-func removePoolWithContext(ctx context.Context, db *sql.DB, userID, poolID string) error {
-	_, err := db.ExecContext(ctx, "DELETE FROM pools WHERE userID = ? AND poolID = ?", userID, poolID)
+func updatePoolBalanceWithContext(ctx context.Context, db *sql.DB, userID, poolID string, balance Amount) error {
+	_, err := db.ExecContext(ctx, "UPDATE pools SET balance_atoms = ? WHERE poolID = ? AND userID = ?", balance, poolID, userID)
 	return err
 }
 
-func getPoolBalanceFromDbWithContext(ctx context.Context, db *sql.DB, userID, poolID string) (int64, error) {
-	var balance int64
-	err := db.QueryRowContext(ctx, "SELECT balance FROM pools WHERE userID = ? AND poolID = ?", userID, poolID).Scan(&balance)
+func getPoolBalanceFromDbWithContext(ctx context.Context, db *sql.DB, userID, poolID string) (Amount, error) {
+	var balance Amount
+	err := db.QueryRowContext(ctx, "SELECT balance_atoms FROM pools WHERE userID = ? AND poolID = ?", userID, poolID).Scan(&balance)
 	return balance, err
 }
 
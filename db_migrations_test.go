@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateDBConvertsWholeMLBalances(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	_, err = legacy.Exec(`CREATE TABLE pools (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		userID TEXT NOT NULL,
+		poolID TEXT NOT NULL,
+		balance INTEGER DEFAULT 0,
+		UNIQUE(userID, poolID) ON CONFLICT IGNORE
+	)`)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if _, err := legacy.Exec("INSERT INTO pools (userID, poolID, balance) VALUES ('u1', 'p1', 42)"); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	_ = legacy.Close()
+
+	db, err := initDB(dbPath)
+	if err != nil {
+		t.Fatalf("initDB failed: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	store, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatalf("NewSQLStore failed: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	balance, err := store.GetPoolBalance(context.Background(), "u1", "p1")
+	if err != nil {
+		t.Fatalf("GetPoolBalance failed: %v", err)
+	}
+	if balance.Cmp(AmountFromML(42)) != 0 {
+		t.Fatalf("expected 42 ML, got %s atoms", balance)
+	}
+
+	for _, table := range []string{"pools", "delegations", "addresses"} {
+		var columns int
+		if err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = 'balance'", table).Scan(&columns); err != nil {
+			t.Fatalf("failed to read the columns of %s: %v", table, err)
+		}
+		if columns != 0 {
+			t.Fatalf("expected the legacy balance column of %s to be dropped", table)
+		}
+	}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("failed to read user_version: %v", err)
+	}
+	if version != len(migrations) {
+		t.Fatalf("expected user_version=%d, got %d", len(migrations), version)
+	}
+
+	db2, err := initDB(dbPath)
+	if err != nil {
+		t.Fatalf("re-running initDB failed: %v", err)
+	}
+	_ = db2.Close()
+}
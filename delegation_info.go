@@ -0,0 +1,14 @@
+package main
+
+// DelegationInfo is the delegation record returned by
+// /api/v2/delegation/{id}.
+type DelegationInfo struct {
+	DelegationID        string
+	PoolID              string
+	SpendDestination    string
+	CreationBlockHeight int64
+	Balance             Amount
+	// Height is the tip of the API server that answered, read before the
+	// delegation; 0 when unknown or listed.
+	Height int64
+}
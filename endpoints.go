@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultHealthCheckInterval = 30 * time.Second
+
+// EndpointStatus is what admin diagnostics show about one API server.
+type EndpointStatus struct {
+	Network   string
+	BaseURL   string
+	Healthy   bool
+	Active    bool
+	LastError string
+	CheckedAt time.Time
+	// Circuit is the state of the network's circuit breaker.
+	Circuit string
+}
+
+// endpointStatusReporter is implemented by clients that talk to API servers
+// directly, as opposed to test fakes.
+type endpointStatusReporter interface {
+	EndpointStatuses() []EndpointStatus
+}
+
+// circuitStateReporter is implemented by clients with a circuit breaker.
+type circuitStateReporter interface {
+	CircuitOpen() bool
+}
+
+type endpoint struct {
+	baseURL   string
+	healthy   bool
+	lastError string
+	checkedAt time.Time
+}
+
+// endpointSet is an ordered list of API servers for one network. Requests go
+// to the first healthy one; every endpoint starts out healthy.
+type endpointSet struct {
+	mu        sync.Mutex
+	endpoints []endpoint
+}
+
+func newEndpointSet(baseURLs []string) *endpointSet {
+	set := &endpointSet{}
+	for _, baseURL := range baseURLs {
+		set.endpoints = append(set.endpoints, endpoint{baseURL: baseURL, healthy: true})
+	}
+	return set
+}
+
+// candidates returns the healthy endpoints in order of preference, or all of
+// them when none is healthy so a request is still attempted.
+func (s *endpointSet) candidates() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var healthy, all []string
+	for _, e := range s.endpoints {
+		all = append(all, e.baseURL)
+		if e.healthy {
+			healthy = append(healthy, e.baseURL)
+		}
+	}
+	if len(healthy) == 0 {
+		return all
+	}
+	return healthy
+}
+
+func (s *endpointSet) active() string {
+	return s.candidates()[0]
+}
+
+func (s *endpointSet) mark(baseURL string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.endpoints {
+		e := &s.endpoints[i]
+		if e.baseURL != baseURL {
+			continue
+		}
+		healthy := err == nil
+		if healthy != e.healthy {
+			if healthy {
+				log.Printf("API server %s is healthy again", baseURL)
+			} else {
+				log.Printf("API server %s is unhealthy: %v", baseURL, err)
+			}
+		}
+		e.healthy = healthy
+		e.lastError = ""
+		if err != nil {
+			e.lastError = err.Error()
+		}
+		e.checkedAt = time.Now()
+	}
+}
+
+func (s *endpointSet) statuses() []EndpointStatus {
+	active := s.active()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]EndpointStatus, 0, len(s.endpoints))
+	for _, e := range s.endpoints {
+		statuses = append(statuses, EndpointStatus{
+			BaseURL:   e.baseURL,
+			Healthy:   e.healthy,
+			Active:    e.baseURL == active,
+			LastError: e.lastError,
+			CheckedAt: e.checkedAt,
+		})
+	}
+	return statuses
+}
+
+// isEndpointFailure reports whether err says the server itself is broken, as
+// opposed to an answer about the looked up ID.
+func isEndpointFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return errors.Is(err, ErrServer) || errors.Is(err, ErrMalformedResponse)
+	}
+	return true
+}
+
+// withFailover runs call against the preferred endpoint and moves on to the
+// next one when the server fails. Endpoints that fail are marked unhealthy
+// until the next health check.
+func withFailover[T any](ctx context.Context, endpoints *endpointSet, call func(baseURL string) (T, error)) (T, error) {
+	var zero T
+	var lastErr error
+	for _, baseURL := range endpoints.candidates() {
+		result, err := call(baseURL)
+		if err == nil || !isEndpointFailure(err) || ctx.Err() != nil {
+			return result, err
+		}
+		endpoints.mark(baseURL, err)
+		lastErr = err
+	}
+	return zero, lastErr
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPBalanceClientFailsOver(t *testing.T) {
+	var primaryDown atomic.Bool
+	primaryDown.Store(true)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if primaryDown.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"blocks": 10, "staker_balance": {"atoms": "100000000000"}}`))
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"blocks": 10, "staker_balance": {"atoms": "200000000000"}}`))
+	}))
+	defer backup.Close()
+
+	client := NewHTTPBalanceClient(primary.URL, backup.URL)
+	status, err := client.GetPoolStatus(context.Background(), "mpool1test")
+	if err != nil {
+		t.Fatalf("GetPoolStatus failed: %v", err)
+	}
+	if status.Balance.Cmp(AmountFromML(2)) != 0 {
+		t.Fatalf("expected the backup's answer, got %s", status.Balance)
+	}
+	if active := client.endpoints.active(); active != backup.URL {
+		t.Fatalf("expected the backup to be in use, got %s", active)
+	}
+
+	primaryDown.Store(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.RunHealthChecks(ctx, time.Hour)
+		close(done)
+	}()
+	deadline := time.After(2 * time.Second)
+	for client.endpoints.active() != primary.URL {
+		select {
+		case <-deadline:
+			t.Fatal("timeout waiting for the primary to be healthy again")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+}
+
+func TestHTTPBalanceClientDoesNotFailOverOnNotFound(t *testing.T) {
+	var backupCalls atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupCalls.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer backup.Close()
+
+	client := NewHTTPBalanceClient(primary.URL, backup.URL)
+	status, err := client.GetPoolStatus(context.Background(), "mpool1test")
+	if err != nil || status.State != PoolStateNotFound {
+		t.Fatalf("expected not found, got %+v, %v", status, err)
+	}
+	if backupCalls.Load() != 0 {
+		t.Fatal("a not found answer must not fail over")
+	}
+}
+
+func TestURLListAcceptsStringOrList(t *testing.T) {
+	var config Config
+	if err := json.Unmarshal([]byte(`{"api_base_url": "http://one"}`), &config); err != nil {
+		t.Fatalf("unmarshal string failed: %v", err)
+	}
+	if len(config.APIBaseURL) != 1 || config.APIBaseURL[0] != "http://one" {
+		t.Fatalf("unexpected list %v", config.APIBaseURL)
+	}
+	if err := json.Unmarshal([]byte(`{"api_base_url": ["http://one", "http://two"]}`), &config); err != nil {
+		t.Fatalf("unmarshal list failed: %v", err)
+	}
+	if len(config.APIBaseURL) != 2 || config.APIBaseURL[1] != "http://two" {
+		t.Fatalf("unexpected list %v", config.APIBaseURL)
+	}
+	if err := json.Unmarshal([]byte(`{"api_base_url": 5}`), &config); err == nil {
+		t.Fatal("expected an error for a number")
+	}
+}
@@ -0,0 +1,615 @@
+// Package fakeapi is an in-memory stand-in for the Mintlayer API server. It
+// answers the endpoints the bot uses with the same JSON shapes, from state
+// that tests or a developer change while it runs: balances move, blocks get
+// produced and the server can be made to fail.
+//
+// Amounts are given in atoms as decimal strings, as the API server reports
+// them; an empty amount is served as "0".
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pool is a stake pool as served by /api/v2/pool/{id}.
+type Pool struct {
+	StakerBalance      string `json:"staker_balance"`
+	DelegationsBalance string `json:"delegations_balance,omitempty"`
+	CostPerBlock       string `json:"cost_per_block,omitempty"`
+	// MarginRatioPerThousand is served verbatim, e.g. "0.025" or "2.5%".
+	MarginRatioPerThousand  string `json:"margin_ratio_per_thousand,omitempty"`
+	VRFPublicKey            string `json:"vrf_public_key,omitempty"`
+	StakerDestination       string `json:"staker_destination,omitempty"`
+	DecommissionDestination string `json:"decommission_destination,omitempty"`
+}
+
+// Delegation is a delegation as served by /api/v2/delegation/{id}.
+type Delegation struct {
+	PoolID              string `json:"pool_id"`
+	Balance             string `json:"balance"`
+	SpendDestination    string `json:"spend_destination,omitempty"`
+	CreationBlockHeight int64  `json:"creation_block_height,omitempty"`
+}
+
+// Address is an address as served by /api/v2/address/{address}. Tokens maps
+// token IDs to atoms.
+type Address struct {
+	CoinBalance string            `json:"coin_balance"`
+	Tokens      map[string]string `json:"tokens,omitempty"`
+}
+
+// Transaction is a transaction as served by /api/v2/transaction/{id}.
+// Inputs are the coins it spends. A BlockHeight of 0 means it is still in
+// the mempool. It is listed in the history of every address it spends from
+// or pays to.
+type Transaction struct {
+	BlockHeight int64      `json:"block_height,omitempty"`
+	Time        time.Time  `json:"time,omitempty"`
+	Inputs      []Transfer `json:"inputs,omitempty"`
+	Outputs     []Transfer `json:"outputs,omitempty"`
+}
+
+// Transfer is an amount of coins, in atoms, held by or paid to Destination.
+type Transfer struct {
+	Destination string `json:"destination"`
+	Amount      string `json:"amount"`
+}
+
+// Token is token metadata as served by /api/v2/token/{id}.
+type Token struct {
+	Ticker   string `json:"ticker"`
+	Decimals int    `json:"decimals"`
+}
+
+// Block is a block on the fake main chain. AddBlock fills in a zero ID or
+// Time.
+type Block struct {
+	ID     string    `json:"id,omitempty"`
+	Time   time.Time `json:"time,omitempty"`
+	PoolID string    `json:"pool_id"`
+	Reward string    `json:"reward,omitempty"`
+}
+
+// State is the whole scriptable state. It is also the JSON format of scenario
+// files and of PUT /fake/state, which merges the given entries.
+type State struct {
+	Pools       map[string]Pool       `json:"pools,omitempty"`
+	Delegations map[string]Delegation `json:"delegations,omitempty"`
+	Addresses   map[string]Address    `json:"addresses,omitempty"`
+	Tokens      map[string]Token      `json:"tokens,omitempty"`
+	// Transactions are added in order, so later ones are newer.
+	Transactions map[string]Transaction `json:"transactions,omitempty"`
+	Blocks       []Block                `json:"blocks,omitempty"`
+}
+
+// API serves the fake endpoints. The zero value is not usable; call New.
+type API struct {
+	mu          sync.Mutex
+	pools       map[string]Pool
+	delegations map[string]Delegation
+	addresses   map[string]Address
+	tokens      map[string]Token
+	txs         map[string]Transaction
+	txOrder     []string
+	blocks      []Block
+	faultStatus int
+	retryAfter  time.Duration
+	requests    int
+}
+
+func New() *API {
+	return &API{
+		pools:       make(map[string]Pool),
+		delegations: make(map[string]Delegation),
+		addresses:   make(map[string]Address),
+		tokens:      make(map[string]Token),
+		txs:         make(map[string]Transaction),
+	}
+}
+
+// Server is an API listening on a local httptest server.
+type Server struct {
+	*API
+	*httptest.Server
+}
+
+// NewServer starts an API on a local port; Close it when done.
+func NewServer() *Server {
+	api := New()
+	return &Server{API: api, Server: httptest.NewServer(api)}
+}
+
+func (a *API) SetPool(id string, pool Pool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pools[id] = pool
+}
+
+// PoolIDs lists the known pools in sorted order.
+func (a *API) PoolIDs() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ids := make([]string, 0, len(a.pools))
+	for id := range a.pools {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RemovePool makes the pool unknown, answered with 404.
+func (a *API) RemovePool(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.pools, id)
+}
+
+func (a *API) SetDelegation(id string, delegation Delegation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.delegations[id] = delegation
+}
+
+func (a *API) RemoveDelegation(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.delegations, id)
+}
+
+func (a *API) SetAddress(address string, state Address) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.addresses[address] = state
+}
+
+func (a *API) SetToken(id string, token Token) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens[id] = token
+}
+
+// SetTransaction adds a transaction, or replaces it, e.g. to confirm one
+// from the mempool. A new transaction is the newest among those at its
+// height.
+func (a *API) SetTransaction(id string, tx Transaction) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.setTransactionLocked(id, tx)
+}
+
+func (a *API) setTransactionLocked(id string, tx Transaction) {
+	if _, ok := a.txs[id]; !ok {
+		a.txOrder = append(a.txOrder, id)
+	}
+	a.txs[id] = tx
+}
+
+// RemoveTransaction drops a transaction, as when it is evicted from the
+// mempool or its block is reorganised away.
+func (a *API) RemoveTransaction(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.txs, id)
+	a.txOrder = slices.DeleteFunc(a.txOrder, func(other string) bool { return other == id })
+}
+
+// AddBlock appends a block to the chain and returns its height. The first
+// block has height 1.
+func (a *API) AddBlock(block Block) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.addBlockLocked(block)
+}
+
+func (a *API) addBlockLocked(block Block) int64 {
+	height := int64(len(a.blocks)) + 1
+	if block.ID == "" {
+		block.ID = fmt.Sprintf("%064x", height)
+	}
+	if block.Time.IsZero() {
+		block.Time = time.Now()
+	}
+	a.blocks = append(a.blocks, block)
+	return height
+}
+
+// TipHeight is the height of the last block, 0 for an empty chain.
+func (a *API) TipHeight() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int64(len(a.blocks))
+}
+
+// Apply merges state into the API: entries replace those with the same ID and
+// blocks are appended.
+func (a *API) Apply(state State) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, pool := range state.Pools {
+		a.pools[id] = pool
+	}
+	for id, delegation := range state.Delegations {
+		a.delegations[id] = delegation
+	}
+	for address, balance := range state.Addresses {
+		a.addresses[address] = balance
+	}
+	for id, token := range state.Tokens {
+		a.tokens[id] = token
+	}
+	txIDs := make([]string, 0, len(state.Transactions))
+	for id := range state.Transactions {
+		txIDs = append(txIDs, id)
+	}
+	sort.Strings(txIDs)
+	for _, id := range txIDs {
+		a.setTransactionLocked(id, state.Transactions[id])
+	}
+	for _, block := range state.Blocks {
+		a.addBlockLocked(block)
+	}
+}
+
+// Fail makes every API request answer status until Recover is called. A
+// non-zero retryAfter is sent as the Retry-After header.
+func (a *API) Fail(status int, retryAfter time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.faultStatus = status
+	a.retryAfter = retryAfter
+}
+
+func (a *API) Recover() {
+	a.Fail(0, 0)
+}
+
+// Requests is the number of API requests served so far, failed ones
+// included. Requests to the /fake/ control endpoints are not counted.
+func (a *API) Requests() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.requests
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/fake/") {
+		a.serveControl(w, r)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests++
+	if a.faultStatus != 0 {
+		if a.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((a.retryAfter+time.Second-1)/time.Second)))
+		}
+		writeError(w, a.faultStatus, http.StatusText(a.faultStatus))
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/api/v1/blocks":
+		writeJSON(w, map[string]any{"blocks": len(a.blocks)})
+	case strings.HasPrefix(path, "/api/v2/chain/"):
+		height, err := strconv.ParseInt(strings.TrimPrefix(path, "/api/v2/chain/"), 10, 64)
+		if err != nil || height < 1 || height > int64(len(a.blocks)) {
+			writeError(w, http.StatusNotFound, "Block not found")
+			return
+		}
+		writeJSON(w, a.blocks[height-1].ID)
+	case strings.HasPrefix(path, "/api/v2/block/"):
+		a.serveBlock(w, strings.TrimPrefix(path, "/api/v2/block/"))
+	case path == "/api/v2/pool":
+		a.servePools(w, r)
+	case strings.HasPrefix(path, "/api/v2/pool/") && strings.HasSuffix(path, "/delegations"):
+		a.servePoolDelegations(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/api/v2/pool/"), "/delegations"))
+	case strings.HasPrefix(path, "/api/v2/pool/"):
+		pool, ok := a.pools[strings.TrimPrefix(path, "/api/v2/pool/")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Stake pool not found")
+			return
+		}
+		writeJSON(w, poolJSON(pool))
+	case strings.HasPrefix(path, "/api/v2/delegation/"):
+		delegation, ok := a.delegations[strings.TrimPrefix(path, "/api/v2/delegation/")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Delegation not found")
+			return
+		}
+		writeJSON(w, delegationJSON(delegation))
+	case strings.HasPrefix(path, "/api/v2/address/") && strings.HasSuffix(path, "/delegations"):
+		a.serveAddressDelegations(w, strings.TrimSuffix(strings.TrimPrefix(path, "/api/v2/address/"), "/delegations"))
+	case strings.HasPrefix(path, "/api/v2/address/"):
+		address, ok := a.addresses[strings.TrimPrefix(path, "/api/v2/address/")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Address not found")
+			return
+		}
+		tokens := []map[string]any{}
+		for id, atoms := range address.Tokens {
+			tokens = append(tokens, map[string]any{"token_id": id, "amount": amountJSON(atoms)})
+		}
+		writeJSON(w, map[string]any{
+			"coin_balance":        amountJSON(address.CoinBalance),
+			"locked_coin_balance": amountJSON(""),
+			"tokens":              tokens,
+			"transaction_history": a.addressHistory(strings.TrimPrefix(path, "/api/v2/address/")),
+		})
+	case strings.HasPrefix(path, "/api/v2/transaction/"):
+		id := strings.TrimPrefix(path, "/api/v2/transaction/")
+		tx, ok := a.txs[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Transaction not found")
+			return
+		}
+		writeJSON(w, transactionJSON(id, tx))
+	case strings.HasPrefix(path, "/api/v2/token/"):
+		token, ok := a.tokens[strings.TrimPrefix(path, "/api/v2/token/")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Token not found")
+			return
+		}
+		writeJSON(w, map[string]any{
+			"token_ticker":       map[string]any{"string": token.Ticker},
+			"number_of_decimals": token.Decimals,
+		})
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// servePools lists all pools in ID order, paged by the offset and items
+// query parameters.
+func (a *API) servePools(w http.ResponseWriter, r *http.Request) {
+	ids := make([]string, 0, len(a.pools))
+	for id := range a.pools {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	start, end := pageBounds(r, len(ids))
+	page := []map[string]any{}
+	for _, id := range ids[start:end] {
+		pool := poolJSON(a.pools[id])
+		pool["pool_id"] = id
+		page = append(page, pool)
+	}
+	writeJSON(w, page)
+}
+
+// servePoolDelegations lists a pool's delegations in ID order, paged like
+// servePools.
+func (a *API) servePoolDelegations(w http.ResponseWriter, r *http.Request, poolID string) {
+	if _, ok := a.pools[poolID]; !ok {
+		writeError(w, http.StatusNotFound, "Stake pool not found")
+		return
+	}
+	ids := a.delegationIDs(func(delegation Delegation) bool { return delegation.PoolID == poolID })
+	start, end := pageBounds(r, len(ids))
+	page := []map[string]any{}
+	for _, id := range ids[start:end] {
+		page = append(page, listedDelegationJSON(id, a.delegations[id]))
+	}
+	writeJSON(w, page)
+}
+
+// serveAddressDelegations lists the delegations spendable by an address.
+func (a *API) serveAddressDelegations(w http.ResponseWriter, address string) {
+	list := []map[string]any{}
+	for _, id := range a.delegationIDs(func(delegation Delegation) bool { return delegation.SpendDestination == address }) {
+		list = append(list, listedDelegationJSON(id, a.delegations[id]))
+	}
+	writeJSON(w, list)
+}
+
+// addressHistory lists the IDs of the transactions touching address, newest
+// first: mempool transactions, then by descending block height.
+func (a *API) addressHistory(address string) []string {
+	ids := []string{}
+	for i := len(a.txOrder) - 1; i >= 0; i-- {
+		id := a.txOrder[i]
+		tx := a.txs[id]
+		touches := func(transfer Transfer) bool { return transfer.Destination == address }
+		if slices.ContainsFunc(tx.Inputs, touches) || slices.ContainsFunc(tx.Outputs, touches) {
+			ids = append(ids, id)
+		}
+	}
+	height := func(id string) int64 {
+		if h := a.txs[id].BlockHeight; h > 0 {
+			return h
+		}
+		return math.MaxInt64
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return height(ids[i]) > height(ids[j]) })
+	return ids
+}
+
+func (a *API) delegationIDs(match func(Delegation) bool) []string {
+	ids := make([]string, 0)
+	for id, delegation := range a.delegations {
+		if match(delegation) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// pageBounds applies the offset and items query parameters to a list of n
+// items.
+func pageBounds(r *http.Request, n int) (int, int) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	items, err := strconv.Atoi(r.URL.Query().Get("items"))
+	if err != nil || items <= 0 {
+		items = 10
+	}
+	start := min(max(offset, 0), n)
+	return start, min(start+items, n)
+}
+
+func (a *API) serveBlock(w http.ResponseWriter, id string) {
+	for _, block := range a.blocks {
+		if block.ID != id {
+			continue
+		}
+		var reward []any
+		if block.Reward != "" {
+			reward = append(reward, map[string]any{
+				"type":  "LockThenTransfer",
+				"value": map[string]any{"type": "Coin", "amount": amountJSON(block.Reward)},
+			})
+		}
+		writeJSON(w, map[string]any{
+			"header": map[string]any{
+				"timestamp": map[string]any{"timestamp": block.Time.Unix()},
+				"consensus_data": map[string]any{
+					"PoS": map[string]any{"stake_pool_id": block.PoolID},
+				},
+			},
+			"body": map[string]any{"reward": reward},
+		})
+		return
+	}
+	writeError(w, http.StatusNotFound, "Block not found")
+}
+
+// serveControl handles the endpoints that change the state over HTTP, for use
+// with the standalone binary:
+//
+//	PUT    /fake/state   merge a State
+//	POST   /fake/blocks  append a Block
+//	PUT    /fake/fault   {"status": 503, "retry_after_seconds": 5}
+//	DELETE /fake/fault   recover
+func (a *API) serveControl(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/fake/state" && r.Method == http.MethodPut:
+		var state State
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		a.Apply(state)
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/fake/blocks" && r.Method == http.MethodPost:
+		var block Block
+		if err := json.NewDecoder(r.Body).Decode(&block); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, map[string]any{"height": a.AddBlock(block)})
+	case r.URL.Path == "/fake/fault" && r.Method == http.MethodPut:
+		var fault struct {
+			Status            int `json:"status"`
+			RetryAfterSeconds int `json:"retry_after_seconds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&fault); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		a.Fail(fault.Status, time.Duration(fault.RetryAfterSeconds)*time.Second)
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/fake/fault" && r.Method == http.MethodDelete:
+		a.Recover()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func poolJSON(pool Pool) map[string]any {
+	body := map[string]any{
+		"staker_balance":           amountJSON(pool.StakerBalance),
+		"delegations_balance":      amountJSON(pool.DelegationsBalance),
+		"cost_per_block":           amountJSON(pool.CostPerBlock),
+		"vrf_public_key":           pool.VRFPublicKey,
+		"decommission_destination": pool.DecommissionDestination,
+	}
+	if pool.StakerDestination != "" {
+		body["staker_destination"] = pool.StakerDestination
+	}
+	if pool.MarginRatioPerThousand != "" {
+		body["margin_ratio_per_thousand"] = pool.MarginRatioPerThousand
+	}
+	return body
+}
+
+func delegationJSON(delegation Delegation) map[string]any {
+	return map[string]any{
+		"pool_id":               delegation.PoolID,
+		"balance":               amountJSON(delegation.Balance),
+		"spend_destination":     delegation.SpendDestination,
+		"creation_block_height": delegation.CreationBlockHeight,
+	}
+}
+
+func listedDelegationJSON(id string, delegation Delegation) map[string]any {
+	body := delegationJSON(delegation)
+	body["delegation_id"] = id
+	return body
+}
+
+func transactionJSON(id string, tx Transaction) map[string]any {
+	inputs := []map[string]any{}
+	for _, input := range tx.Inputs {
+		inputs = append(inputs, map[string]any{
+			"input": map[string]any{"input_type": "UTXO"},
+			"utxo":  transferJSON(input),
+		})
+	}
+	outputs := []map[string]any{}
+	for _, output := range tx.Outputs {
+		outputs = append(outputs, transferJSON(output))
+	}
+	body := map[string]any{
+		"id":      id,
+		"inputs":  inputs,
+		"outputs": outputs,
+	}
+	if tx.BlockHeight > 0 {
+		body["block_id"] = fmt.Sprintf("%064x", tx.BlockHeight)
+		body["block_height"] = tx.BlockHeight
+		body["timestamp"] = strconv.FormatInt(tx.Time.Unix(), 10)
+	}
+	return body
+}
+
+func transferJSON(transfer Transfer) map[string]any {
+	return map[string]any{
+		"type":        "Transfer",
+		"destination": transfer.Destination,
+		"value":       map[string]any{"type": "Coin", "amount": amountJSON(transfer.Amount)},
+	}
+}
+
+func amountJSON(atoms string) map[string]any {
+	if atoms == "" {
+		atoms = "0"
+	}
+	return map[string]any{"atoms": atoms}
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": message})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+
+	"mintlayer_bot/fakeapi"
+)
+
+// TestNotificationsAgainstFakeAPI runs the notification checks over real HTTP
+// and JSON parsing, driving balance changes and blocks through the fake API
+// server.
+func TestNotificationsAgainstFakeAPI(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	server.SetPool("p1", fakeapi.Pool{StakerBalance: AmountFromML(40000).String(), MarginRatioPerThousand: "0.025"})
+	server.SetDelegation("d1", fakeapi.Delegation{PoolID: "p1", Balance: AmountFromML(100).String()})
+	server.SetAddress("a1", fakeapi.Address{CoinBalance: AmountFromML(5).String()})
+	server.SetToken(testMainnetTokenID, fakeapi.Token{Ticker: "TKN", Decimals: 2})
+	server.AddBlock(fakeapi.Block{PoolID: "p2"})
+
+	store, exec := newTestSQLStore(t)
+	ctx := context.Background()
+	exec("INSERT INTO pools (userID, poolID, balance_atoms, state) VALUES ('u1', 'p1', ?, 'active')", AmountFromML(40000).String())
+	exec("INSERT INTO delegations (userID, delegationID, balance_atoms) VALUES ('u1', 'd1', ?)", AmountFromML(100).String())
+	if err := store.AddMonitoredAddress(ctx, "u1", "a1", mainnetNetwork, 0, true, 1); err != nil {
+		t.Fatalf("AddMonitoredAddress failed: %v", err)
+	}
+	exec("UPDATE addresses SET balance_atoms = ? WHERE address = 'a1'", AmountFromML(5).String())
+	if err := store.SetPoolBlockNotifications(ctx, "u1", "p1", true, 1); err != nil {
+		t.Fatalf("SetPoolBlockNotifications failed: %v", err)
+	}
+
+	client := NewHTTPBalanceClient(server.URL)
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.tip = NewChainTipWatcher(client, time.Minute)
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	check := func() {
+		app.tip.poll(ctx)
+		height, _ := app.tip.Tip()
+		app.notifyPoolsBalanceChanges(ctx, "u1", 1, height)
+		app.notifyDelegationsBalanceChanges(ctx, "u1", 1, height)
+		app.notifyAddressesBalanceChanges(ctx, "u1", 1, height)
+		app.notifyPoolBlocks(ctx, "u1", 1)
+	}
+
+	check()
+	if len(messages) != 0 {
+		t.Fatalf("expected no messages before any change, got %v", messages)
+	}
+
+	blockTime := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	server.AddBlock(fakeapi.Block{PoolID: "p1", Time: blockTime, Reward: AmountFromML(202).String()})
+	server.SetPool("p1", fakeapi.Pool{StakerBalance: AmountFromML(40010).String(), MarginRatioPerThousand: "0.025"})
+	server.SetDelegation("d1", fakeapi.Delegation{PoolID: "p1", Balance: AmountFromML(150).String()})
+	server.SetAddress("a1", fakeapi.Address{CoinBalance: AmountFromML(3).String(), Tokens: map[string]string{testMainnetTokenID: "250"}})
+	check()
+
+	expected := []string{
+		"`p1`: \\+10 ML at block `2`",
+		"`d1`: \\+50 ML at block `2`",
+		"`a1`: \\-2 ML at block `2`",
+		"`a1`: \\+2.5 TKN at block `2`",
+		"`p1`: produced block `2` at 2026-10-17 12:00:00 UTC, reward 202 ML",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected messages:\nexpected: %q\ngot:      %q", expected, messages)
+	}
+
+	messages = nil
+	server.SetPool("p1", fakeapi.Pool{StakerBalance: "0"})
+	check()
+	if len(messages) != 1 || messages[0] != "`p1`: `decommissioned` (\\-40,010 ML) at block `2`" {
+		t.Fatalf("expected the decommission to be announced, got %v", messages)
+	}
+}
+
+func TestHTTPBalanceClientAgainstFailingFakeAPI(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	server.SetDelegation("d1", fakeapi.Delegation{PoolID: "p1", Balance: "123"})
+
+	client := NewHTTPBalanceClient(server.URL)
+	server.Fail(http.StatusServiceUnavailable, 0)
+	if _, err := client.GetDelegationInfo(context.Background(), "d1"); err == nil {
+		t.Fatal("expected an error from the failing server")
+	}
+
+	server.Recover()
+	info, err := client.GetDelegationInfo(context.Background(), "d1")
+	if err != nil {
+		t.Fatalf("GetDelegationInfo failed: %v", err)
+	}
+	if info.PoolID != "p1" || info.Balance.Cmp(AmountFromAtoms(123)) != 0 {
+		t.Fatalf("unexpected delegation info %+v", info)
+	}
+}
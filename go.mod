@@ -5,6 +5,7 @@ go 1.21.1
 require (
 	github.com/btcsuite/btcd/btcutil v1.1.5
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
+	github.com/go-telegram/bot v1.2.2
 	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/tidwall/gjson v1.17.1
 	golang.org/x/text v0.3.3
@@ -12,7 +13,6 @@ require (
 
 require (
 	github.com/btcsuite/btcutil v1.0.2 // indirect
-	github.com/go-telegram/bot v1.2.2 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 )
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+// idKind is what a Mintlayer ID refers to. Every kind is encoded as bech32m
+// with an HRP naming both the kind and the network, e.g. tpool for testnet
+// pools.
+type idKind int
+
+const (
+	poolIDKind idKind = iota
+	delegationIDKind
+	addressKind
+	tokenIDKind
+)
+
+var idKinds = []idKind{poolIDKind, delegationIDKind, addressKind, tokenIDKind}
+
+func (k idKind) String() string {
+	switch k {
+	case poolIDKind:
+		return "pool ID"
+	case delegationIDKind:
+		return "delegation ID"
+	case addressKind:
+		return "address"
+	default:
+		return "token ID"
+	}
+}
+
+// withArticle prefixes noun with its indefinite article, e.g. "an address".
+func withArticle(noun string) string {
+	if noun != "" && strings.ContainsRune("aeiouAEIOU", rune(noun[0])) {
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+func (k idKind) hrp(network Network) string {
+	switch k {
+	case poolIDKind:
+		return network.PoolHRP
+	case delegationIDKind:
+		return network.DelegationHRP
+	case addressKind:
+		return network.AddressHRP
+	default:
+		return network.TokenHRP
+	}
+}
+
+// parsedID is an ID checked to be bech32m with the HRP of its kind on
+// Network. It is kept in lower case.
+type parsedID struct {
+	id      string
+	Network Network
+}
+
+func (p parsedID) String() string {
+	return p.id
+}
+
+type (
+	PoolID       struct{ parsedID }
+	DelegationID struct{ parsedID }
+	Address      struct{ parsedID }
+	TokenID      struct{ parsedID }
+)
+
+// ParsePoolID checks that s is a pool ID of one of the networks. The error
+// says what s is instead and what was wanted, e.g. "this is a testnet
+// delegation ID, not a mainnet or testnet pool ID", and is meant to be shown
+// to the user.
+func (n Networks) ParsePoolID(s string) (PoolID, error) {
+	id, err := n.parseID(s, poolIDKind)
+	return PoolID{id}, err
+}
+
+func (n Networks) ParseDelegationID(s string) (DelegationID, error) {
+	id, err := n.parseID(s, delegationIDKind)
+	return DelegationID{id}, err
+}
+
+func (n Networks) ParseAddress(s string) (Address, error) {
+	id, err := n.parseID(s, addressKind)
+	return Address{id}, err
+}
+
+func (n Networks) ParseTokenID(s string) (TokenID, error) {
+	id, err := n.parseID(s, tokenIDKind)
+	return TokenID{id}, err
+}
+
+// parseID checks the encoding of s, then finds the network and kind its HRP
+// belongs to. The first network using an HRP wins, see detect.
+func (n Networks) parseID(s string, want idKind) (parsedID, error) {
+	hrp, _, version, err := bech32.DecodeGeneric(s)
+	if err != nil {
+		return parsedID{}, fmt.Errorf("this is not a valid %s, check it for typos", want)
+	}
+	if version != bech32.VersionM {
+		return parsedID{}, fmt.Errorf("this has a bech32 checksum, Mintlayer IDs use bech32m")
+	}
+	var expected, names []string
+	for _, network := range n {
+		names = append(names, network.Name)
+	}
+	wanted := withArticle(fmt.Sprintf("%s %s", strings.Join(names, " or "), want))
+	for _, network := range n {
+		expected = append(expected, want.hrp(network))
+		for _, kind := range idKinds {
+			if kind.hrp(network) != hrp {
+				continue
+			}
+			if kind != want {
+				return parsedID{}, fmt.Errorf("this is %s, not %s", withArticle(fmt.Sprintf("%s %s", network.Name, kind)), wanted)
+			}
+			return parsedID{id: strings.ToLower(s), Network: network}, nil
+		}
+	}
+	return parsedID{}, fmt.Errorf("this is not %s (expected prefix %s)", wanted, strings.Join(expected, ", "))
+}
+
+// isBech32m reports whether id is bech32m encoded, whatever its HRP. The
+// store checks IDs with it; commands parse them with their network first.
+func isBech32m(id string) bool {
+	_, _, version, err := bech32.DecodeGeneric(id)
+	return err == nil && version == bech32.VersionM
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func TestParseIDs(t *testing.T) {
+	networks := defaultNetworks()
+	_, data, err := bech32.Decode(testMainnetPoolID)
+	if err != nil {
+		t.Fatalf("decoding the test pool ID failed: %v", err)
+	}
+	bech32PoolID, _ := bech32.Encode("mpool", data)
+	unknownPoolID, _ := bech32.EncodeM("xpool", data)
+	tokenID, _ := bech32.EncodeM("tmltk", data)
+
+	tests := []struct {
+		name    string
+		parse   func(string) (parsedID, error)
+		id      string
+		network string
+		errText string
+	}{
+		{"mainnet pool", parsePool(networks), testMainnetPoolID, mainnetNetwork, ""},
+		{"upper case pool", parsePool(networks), strings.ToUpper(testTestnetPoolID), testnetNetwork, ""},
+		{"testnet delegation", parseDelegation(networks), testTestnetDelegationID, testnetNetwork, ""},
+		{"testnet address", parseAddress(networks), testTestnetAddress, testnetNetwork, ""},
+		{"testnet token", parseToken(networks), tokenID, testnetNetwork, ""},
+		{"delegation as pool", parsePool(networks), testTestnetDelegationID, "", "this is a testnet delegation ID, not a mainnet or testnet pool ID"},
+		{"delegation as mainnet pool", parsePool(Networks{defaultNetworks().Primary()}), testTestnetDelegationID, "", "this is not a mainnet pool ID (expected prefix mpool)"},
+		{"pool as address", parseAddress(networks), testMainnetPoolID, "", "this is a mainnet pool ID, not a mainnet or testnet address"},
+		{"bech32 checksum", parsePool(networks), bech32PoolID, "", "this has a bech32 checksum, Mintlayer IDs use bech32m"},
+		{"typo", parsePool(networks), testMainnetPoolID[:len(testMainnetPoolID)-1] + "q", "", "this is not a valid pool ID, check it for typos"},
+		{"unknown network", parsePool(networks), unknownPoolID, "", "this is not a mainnet or testnet pool ID (expected prefix mpool, tpool)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := tt.parse(tt.id)
+			if tt.errText != "" {
+				if err == nil || err.Error() != tt.errText {
+					t.Fatalf("expected error %q, got %v", tt.errText, err)
+				}
+				return
+			}
+			if err != nil || id.Network.Name != tt.network || id.String() != strings.ToLower(tt.id) {
+				t.Fatalf("expected %s on %s, got %q on %q, %v", tt.id, tt.network, id.String(), id.Network.Name, err)
+			}
+		})
+	}
+}
+
+func parsePool(n Networks) func(string) (parsedID, error) {
+	return func(s string) (parsedID, error) {
+		id, err := n.ParsePoolID(s)
+		return id.parsedID, err
+	}
+}
+
+func parseDelegation(n Networks) func(string) (parsedID, error) {
+	return func(s string) (parsedID, error) {
+		id, err := n.ParseDelegationID(s)
+		return id.parsedID, err
+	}
+}
+
+func parseAddress(n Networks) func(string) (parsedID, error) {
+	return func(s string) (parsedID, error) {
+		id, err := n.ParseAddress(s)
+		return id.parsedID, err
+	}
+}
+
+func parseToken(n Networks) func(string) (parsedID, error) {
+	return func(s string) (parsedID, error) {
+		id, err := n.ParseTokenID(s)
+		return id.parsedID, err
+	}
+}
+
+func TestRemovePoolRejectsDelegationID(t *testing.T) {
+	store, _ := newTestSQLStore(t)
+	ctx := context.Background()
+	if err := store.AddDelegation(ctx, "7", testTestnetDelegationID, testnetNetwork); err != nil {
+		t.Fatalf("AddDelegation failed: %v", err)
+	}
+	app := NewApp(store, &noopBalanceClient{}, nil, NewNotificationManager(), "", ctx)
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	app.removePoolHandler(ctx, nil, &models.Update{
+		Message: &models.Message{
+			Text: "/pool_remove " + testTestnetDelegationID,
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	})
+	if expected := "Invalid pool ID: this is a testnet delegation ID, not a mainnet or testnet pool ID"; lastMessage != expected {
+		t.Fatalf("unexpected reply:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+	if delegations, _ := store.GetDelegations(ctx, "7"); len(delegations) != 1 {
+		t.Fatalf("expected the delegation to stay tracked, got %v", delegations)
+	}
+}
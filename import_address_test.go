@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"mintlayer_bot/fakeapi"
+)
+
+const (
+	testTestnetPoolID2       = "tpool1pc83qygjzv2p29shrqv35xcur50p7gppazmyj6"
+	testTestnetPoolID3       = "tpool1z5tpwxqergd3c8g7ruszzg3rysjjvfegtjcg9r"
+	testTestnetDelegationID2 = "tdelg1pc83qygjzv2p29shrqv35xcur50p7gpplkd25j"
+	testTestnetDelegationID3 = "tdelg1z5tpwxqergd3c8g7ruszzg3rysjjvfegfxwxrt"
+)
+
+func TestImportAddressAgainstFakeAPI(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	owner := testTestnetAddress
+	// Enough other pools that the owner's pools are on different pages.
+	for i := 0; i < listPageSize+5; i++ {
+		server.SetPool(fmt.Sprintf("tpool%03d", i), fakeapi.Pool{StakerBalance: AmountFromML(1).String()})
+	}
+	server.SetPool(testTestnetPoolID, fakeapi.Pool{StakerBalance: AmountFromML(40000).String(), DecommissionDestination: owner})
+	server.SetPool(testTestnetPoolID2, fakeapi.Pool{StakerBalance: AmountFromML(50000).String(), StakerDestination: owner})
+	server.SetPool(testTestnetPoolID3, fakeapi.Pool{StakerBalance: "0", DecommissionDestination: owner})
+	server.SetDelegation(testTestnetDelegationID, fakeapi.Delegation{PoolID: "tpool000", Balance: AmountFromML(100).String(), SpendDestination: owner})
+	server.SetDelegation(testTestnetDelegationID2, fakeapi.Delegation{PoolID: "tpool001", Balance: "0", SpendDestination: owner})
+	server.SetDelegation("tdelg-other", fakeapi.Delegation{PoolID: "tpool001", Balance: "5", SpendDestination: "tmt1other"})
+
+	store, exec := newTestSQLStore(t)
+	ctx := context.Background()
+	exec("INSERT INTO pools (userID, poolID, network) VALUES ('7', ?, 'testnet')", testTestnetPoolID)
+	app := NewApp(store, NewHTTPBalanceClient(server.URL), nil, NewNotificationManager(), "", ctx)
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	command := func(text string) string {
+		t.Helper()
+		messages = nil
+		app.importAddressHandler(ctx, nil, &models.Update{
+			Message: &models.Message{
+				Text: text,
+				Chat: models.Chat{ID: 5},
+				From: &models.User{ID: 7},
+			},
+		})
+		return strings.Join(messages, "\n")
+	}
+
+	got := command("/import_address " + owner + " on")
+	expected := "Found for `" + owner + "`:\n" +
+		"Pool `" + testTestnetPoolID2 + "`: 50,000 ML\n" +
+		"Pool `" + testTestnetPoolID + "` (already tracked)\n" +
+		"Delegation `" + testTestnetDelegationID + "`: 100 ML\n" +
+		"Added `1` pools and `1` delegations\n" +
+		"New delegations of this address will be added while notifications are on"
+	if got != expected {
+		t.Fatalf("unexpected reply:\nexpected: %q\ngot:      %q", expected, got)
+	}
+	pools, _ := store.GetPools(ctx, "7")
+	delegations, _ := store.GetDelegations(ctx, "7")
+	if len(pools) != 2 || len(delegations) != 1 || delegations[0] != testTestnetDelegationID {
+		t.Fatalf("unexpected tracked pools %v and delegations %v", pools, delegations)
+	}
+
+	messages = nil
+	app.syncAddressDelegations(ctx, "7", 5)
+	if len(messages) != 0 {
+		t.Fatalf("expected nothing new to sync, got %q", messages)
+	}
+	server.SetDelegation(testTestnetDelegationID2, fakeapi.Delegation{PoolID: "tpool001", Balance: AmountFromML(20).String(), SpendDestination: owner})
+	server.SetDelegation(testTestnetDelegationID3, fakeapi.Delegation{PoolID: "tpool002", Balance: AmountFromML(30).String(), SpendDestination: owner})
+	app.syncAddressDelegations(ctx, "7", 5)
+	expectedSync := []string{
+		"`" + owner + "`: now tracking new delegation `" + testTestnetDelegationID2 + "`",
+		"`" + owner + "`: now tracking new delegation `" + testTestnetDelegationID3 + "`",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expectedSync, "\n") {
+		t.Fatalf("unexpected sync messages:\nexpected: %q\ngot:      %q", expectedSync, messages)
+	}
+	if delegations, _ := store.GetDelegations(ctx, "7"); len(delegations) != 3 {
+		t.Fatalf("expected the new delegations to be tracked, got %v", delegations)
+	}
+
+	command("/import_address " + owner + " off")
+	if synced, err := store.GetSyncedAddresses(ctx, "7"); err != nil || len(synced) != 0 {
+		t.Fatalf("expected syncing to stop, got %v, %v", synced, err)
+	}
+}
+
+func TestImportPoolsAndDelegationsIsAtomic(t *testing.T) {
+	store, _ := newTestSQLStore(t)
+	ctx := context.Background()
+
+	err := store.ImportPoolsAndDelegations(ctx, "7", testnetNetwork, []string{testTestnetPoolID}, []string{testTestnetDelegationID, "not-bech32"})
+	if err == nil {
+		t.Fatal("expected an invalid delegation ID to fail the import")
+	}
+	if pools, _ := store.GetPools(ctx, "7"); len(pools) != 0 {
+		t.Fatalf("expected the import to be rolled back, got pools %v", pools)
+	}
+}
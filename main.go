@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -52,8 +53,52 @@ func main() {
 			log.Printf("Error closing database statements: %v", err)
 		}
 	}()
-	client := NewHTTPBalanceClient(config.APIBaseURL)
-	app := NewApp(store, client, b, NewNotificationManager(), config.AdminUser, ctx)
+	networks, err := buildNetworks(config.APIBaseURL, config.Networks)
+	if err != nil {
+		log.Fatalf("Error in network config: %v", err)
+	}
+	requestsPerSecond, maxInFlight := config.APIRequestsPerSecond, config.APIMaxInFlight
+	if requestsPerSecond == 0 {
+		requestsPerSecond = defaultAPIRequestsPerSecond
+	}
+	if maxInFlight == 0 {
+		maxInFlight = defaultAPIMaxInFlight
+	}
+	httpClient.Transport = &limitedTransport{limiter: newAPILimiter(requestsPerSecond, maxInFlight)}
+	client := NewNetworkClient(networks)
+	client.SetTrackedNetworks(store.GetTrackedNetwork)
+	client.SetCircuitBreaker(config.CircuitBreakerFailures, time.Duration(config.CircuitBreakerCooldownSeconds)*time.Second)
+	go client.RunHealthChecks(ctx, time.Duration(config.HealthCheckSeconds)*time.Second)
+	var appClient BalanceClient = client
+	if config.CacheTTLSeconds >= 0 {
+		appClient = NewCachingBalanceClient(client, time.Duration(config.CacheTTLSeconds)*time.Second)
+	}
+	app := NewApp(store, appClient, b, NewNotificationManager(), config.AdminUser, ctx)
+	app.networks = networks
+	if config.AmountDecimals != nil {
+		app.amountDecimals = *config.AmountDecimals
+	}
+	if config.PriceProvider != nil {
+		app.prices, err = buildPriceProvider(*config.PriceProvider)
+		if err != nil {
+			log.Fatalf("Error in price provider config: %v", err)
+		}
+	}
+	if config.MaxCheckIntervalMinutes > 0 {
+		app.maxCheckInterval = time.Duration(config.MaxCheckIntervalMinutes) * time.Minute
+	}
+	if config.BalanceConfirmations > 0 {
+		app.balanceConfirmations = int64(config.BalanceConfirmations)
+	}
+	app.networkTips = make(map[string]*ChainTipWatcher, len(networks))
+	for _, network := range networks {
+		tip := NewChainTipWatcher(client.For(network.Name), time.Duration(config.TipPollSeconds)*time.Second)
+		app.networkTips[network.Name] = tip
+		go tip.Run(ctx)
+	}
+	app.tip = app.networkTips[networks.Primary().Name]
+	go app.samplePoolsRoutine(ctx)
+	go app.prunePoolSamplesRoutine(ctx)
 	app.registerHandlers()
 	app.recoverPastNotifications(ctx)
 
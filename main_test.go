@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Keep retries of failing test servers from slowing the suite down;
+	// tests of the retry policy set their own values.
+	retryBaseDelay = time.Millisecond
+	retryMaxDelay = 10 * time.Millisecond
+	retryBudget = time.Second
+	os.Exit(m.Run())
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+const (
+	mainnetNetwork = "mainnet"
+	testnetNetwork = "testnet"
+
+	defaultTestnetAPIBaseURL = "https://api-server-lovelace.mintlayer.org"
+)
+
+// Network is a Mintlayer chain the bot can follow: the bech32 prefixes of
+// its IDs and the API server answering for it.
+type Network struct {
+	Name          string
+	PoolHRP       string
+	DelegationHRP string
+	AddressHRP    string
+	TokenHRP      string
+	// APIBaseURLs are the network's API servers in order of preference.
+	APIBaseURLs []string
+}
+
+var (
+	mainnet = Network{
+		Name:          mainnetNetwork,
+		PoolHRP:       "mpool",
+		DelegationHRP: "mdelg",
+		AddressHRP:    "mtc",
+		TokenHRP:      "mmltk",
+		APIBaseURLs:   []string{defaultAPIBaseURL},
+	}
+	testnet = Network{
+		Name:          testnetNetwork,
+		PoolHRP:       "tpool",
+		DelegationHRP: "tdelg",
+		AddressHRP:    "tmt",
+		TokenHRP:      "tmltk",
+		APIBaseURLs:   []string{defaultTestnetAPIBaseURL},
+	}
+)
+
+// Networks is the set of networks the bot follows. The first one is the
+// primary network: its chain tip drives the notification routines.
+type Networks []Network
+
+func defaultNetworks() Networks {
+	return Networks{mainnet, testnet}
+}
+
+func (n Networks) Primary() Network {
+	if len(n) == 0 {
+		return mainnet
+	}
+	return n[0]
+}
+
+func (n Networks) Lookup(name string) (Network, bool) {
+	for _, network := range n {
+		if network.Name == name {
+			return network, true
+		}
+	}
+	return Network{}, false
+}
+
+// trackedNetworkFunc returns the network stored next to a tracked ID, or
+// sql.ErrNoRows when nobody tracks it, like Store.GetTrackedNetwork.
+type trackedNetworkFunc func(ctx context.Context, id string) (string, error)
+
+// Resolve returns the network stored next to id when it is tracked, and
+// otherwise the one its prefix names.
+func (n Networks) Resolve(ctx context.Context, id string, tracked trackedNetworkFunc) (Network, error) {
+	if tracked != nil {
+		name, err := tracked(ctx, id)
+		if err == nil {
+			if network, ok := n.Lookup(name); ok {
+				return network, nil
+			}
+			return Network{}, fmt.Errorf("%s is tracked on %s, which is not configured", id, name)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error reading the network of %s: %v", id, err)
+		}
+	}
+	return n.Detect(id)
+}
+
+// Detect returns the network of any pool, delegation, address or token ID.
+func (n Networks) Detect(id string) (Network, error) {
+	return n.detect(id, "ID", func(network Network) []string {
+		return []string{network.PoolHRP, network.DelegationHRP, network.AddressHRP, network.TokenHRP}
+	})
+}
+
+// detect picks the first network using the HRP of id. Custom networks that
+// reuse the HRPs of a built-in one should override it by name instead.
+func (n Networks) detect(id, kind string, hrps func(Network) []string) (Network, error) {
+	hrp, _, err := bech32.Decode(id)
+	if err != nil {
+		return Network{}, err
+	}
+	var expected []string
+	for _, network := range n {
+		for _, candidate := range hrps(network) {
+			if candidate == hrp {
+				return network, nil
+			}
+			expected = append(expected, candidate)
+		}
+	}
+	return Network{}, fmt.Errorf("this is not %s of a known network (expected prefix %s)", withArticle(kind), strings.Join(expected, ", "))
+}
+
+// NetworkConfig adds a custom network or overrides fields of a built-in one
+// with the same name.
+type NetworkConfig struct {
+	Name          string  `json:"name"`
+	APIBaseURL    URLList `json:"api_base_url,omitempty"`
+	PoolHRP       string  `json:"pool_hrp,omitempty"`
+	DelegationHRP string  `json:"delegation_hrp,omitempty"`
+	AddressHRP    string  `json:"address_hrp,omitempty"`
+	TokenHRP      string  `json:"token_hrp,omitempty"`
+}
+
+// buildNetworks applies the configured overrides to the built-in networks.
+// mainnetAPIBaseURLs is the top-level api_base_url setting.
+func buildNetworks(mainnetAPIBaseURLs []string, configs []NetworkConfig) (Networks, error) {
+	networks := defaultNetworks()
+	if len(mainnetAPIBaseURLs) > 0 {
+		networks[0].APIBaseURLs = mainnetAPIBaseURLs
+	}
+	for _, cfg := range configs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("network without a name")
+		}
+		i := len(networks)
+		for j, network := range networks {
+			if network.Name == cfg.Name {
+				i = j
+			}
+		}
+		if i == len(networks) {
+			networks = append(networks, Network{Name: cfg.Name})
+		}
+		network := &networks[i]
+		if len(cfg.APIBaseURL) > 0 {
+			network.APIBaseURLs = cfg.APIBaseURL
+		}
+		for _, field := range []struct {
+			dst *string
+			src string
+		}{
+			{&network.PoolHRP, cfg.PoolHRP},
+			{&network.DelegationHRP, cfg.DelegationHRP},
+			{&network.AddressHRP, cfg.AddressHRP},
+			{&network.TokenHRP, cfg.TokenHRP},
+		} {
+			if field.src != "" {
+				*field.dst = field.src
+			}
+		}
+		if len(network.APIBaseURLs) == 0 || network.PoolHRP == "" || network.DelegationHRP == "" || network.AddressHRP == "" || network.TokenHRP == "" {
+			return nil, fmt.Errorf("network %s needs api_base_url, pool_hrp, delegation_hrp, address_hrp and token_hrp", cfg.Name)
+		}
+	}
+	return networks, nil
+}
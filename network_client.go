@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NetworkClient sends every lookup to the API server of the network the ID
+// belongs to: the network stored next to a tracked entity, or for IDs
+// nobody tracks the one named by the bech32 HRP. Chain tip and block
+// lookups, which take no ID, go to the primary network.
+type NetworkClient struct {
+	networks Networks
+	clients  map[string]*HTTPBalanceClient
+	tracked  trackedNetworkFunc
+}
+
+func NewNetworkClient(networks Networks) *NetworkClient {
+	clients := make(map[string]*HTTPBalanceClient, len(networks))
+	for _, network := range networks {
+		clients[network.Name] = NewHTTPBalanceClient(network.APIBaseURLs...)
+	}
+	return &NetworkClient{networks: networks, clients: clients}
+}
+
+// For returns the client of a single network, or nil if it is unknown.
+func (c *NetworkClient) For(network string) BalanceClient {
+	client, ok := c.clients[network]
+	if !ok {
+		return nil
+	}
+	return client
+}
+
+// SetTrackedNetworks makes lookups of tracked IDs use the network stored
+// for them; call it before the client is used.
+func (c *NetworkClient) SetTrackedNetworks(tracked trackedNetworkFunc) {
+	c.tracked = tracked
+}
+
+// RunHealthChecks health-checks the API servers of every network until ctx
+// is cancelled.
+func (c *NetworkClient) RunHealthChecks(ctx context.Context, interval time.Duration) {
+	var wg sync.WaitGroup
+	for _, client := range c.clients {
+		wg.Add(1)
+		go func(client *HTTPBalanceClient) {
+			defer wg.Done()
+			client.RunHealthChecks(ctx, interval)
+		}(client)
+	}
+	wg.Wait()
+}
+
+// SetCircuitBreaker applies the circuit breaker settings to every network.
+func (c *NetworkClient) SetCircuitBreaker(failures int, cooldown time.Duration) {
+	for _, client := range c.clients {
+		client.SetCircuitBreaker(failures, cooldown)
+	}
+}
+
+// CircuitOpen reports the primary network's circuit breaker, whose chain tip
+// drives the notification routines. Lookups on other networks fail fast on
+// their own with ErrCircuitOpen.
+func (c *NetworkClient) CircuitOpen() bool {
+	return c.clients[c.networks.Primary().Name].CircuitOpen()
+}
+
+// EndpointStatuses lists the API servers of every network, primary first.
+func (c *NetworkClient) EndpointStatuses() []EndpointStatus {
+	var statuses []EndpointStatus
+	for _, network := range c.networks {
+		for _, status := range c.clients[network.Name].EndpointStatuses() {
+			status.Network = network.Name
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+func (c *NetworkClient) route(ctx context.Context, id string) (BalanceClient, error) {
+	network, err := c.networks.Resolve(ctx, id, c.tracked)
+	if err != nil {
+		return nil, err
+	}
+	return c.clients[network.Name], nil
+}
+
+func (c *NetworkClient) GetTipHeight(ctx context.Context) (int64, error) {
+	return c.clients[c.networks.Primary().Name].GetTipHeight(ctx)
+}
+
+func (c *NetworkClient) GetBlock(ctx context.Context, height int64) (BlockInfo, error) {
+	return c.clients[c.networks.Primary().Name].GetBlock(ctx, height)
+}
+
+func (c *NetworkClient) GetPoolStatus(ctx context.Context, poolID string) (PoolStatus, error) {
+	client, err := c.route(ctx, poolID)
+	if err != nil {
+		return PoolStatus{}, err
+	}
+	return client.GetPoolStatus(ctx, poolID)
+}
+
+func (c *NetworkClient) GetPoolInfo(ctx context.Context, poolID string) (PoolInfo, error) {
+	client, err := c.route(ctx, poolID)
+	if err != nil {
+		return PoolInfo{}, err
+	}
+	return client.GetPoolInfo(ctx, poolID)
+}
+
+func (c *NetworkClient) GetPoolDelegations(ctx context.Context, poolID string) ([]DelegationInfo, error) {
+	client, err := c.route(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetPoolDelegations(ctx, poolID)
+}
+
+func (c *NetworkClient) GetDelegationBalance(ctx context.Context, delegationID string) (Amount, error) {
+	client, err := c.route(ctx, delegationID)
+	if err != nil {
+		return Amount{}, err
+	}
+	return client.GetDelegationBalance(ctx, delegationID)
+}
+
+func (c *NetworkClient) GetDelegationInfo(ctx context.Context, delegationID string) (DelegationInfo, error) {
+	client, err := c.route(ctx, delegationID)
+	if err != nil {
+		return DelegationInfo{}, err
+	}
+	return client.GetDelegationInfo(ctx, delegationID)
+}
+
+func (c *NetworkClient) GetAddressBalance(ctx context.Context, address string) (AddressBalance, error) {
+	client, err := c.route(ctx, address)
+	if err != nil {
+		return AddressBalance{}, err
+	}
+	return client.GetAddressBalance(ctx, address)
+}
+
+func (c *NetworkClient) GetAddressHistory(ctx context.Context, address string, offset, count int) (AddressHistory, error) {
+	client, err := c.route(ctx, address)
+	if err != nil {
+		return AddressHistory{}, err
+	}
+	return client.GetAddressHistory(ctx, address, offset, count)
+}
+
+func (c *NetworkClient) GetAddressTransactions(ctx context.Context, address string, txIDs []string) ([]TransactionInfo, error) {
+	client, err := c.route(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetAddressTransactions(ctx, address, txIDs)
+}
+
+func (c *NetworkClient) GetAddressDelegations(ctx context.Context, address string) ([]DelegationInfo, error) {
+	client, err := c.route(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetAddressDelegations(ctx, address)
+}
+
+func (c *NetworkClient) GetAddressPools(ctx context.Context, address string) ([]PoolInfo, error) {
+	client, err := c.route(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetAddressPools(ctx, address)
+}
+
+func (c *NetworkClient) GetTotalStake(ctx context.Context, poolID string) (Amount, error) {
+	client, err := c.route(ctx, poolID)
+	if err != nil {
+		return Amount{}, err
+	}
+	return client.GetTotalStake(ctx, poolID)
+}
+
+func (c *NetworkClient) GetTokenInfo(ctx context.Context, tokenID string) (TokenInfo, error) {
+	id, err := c.networks.ParseTokenID(tokenID)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	return c.clients[id.Network.Name].GetTokenInfo(ctx, id.String())
+}
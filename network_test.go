@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+const (
+	testMainnetPoolID       = "mpool1qv9pzxqlyckngw6zf9g9whn9d3eh4qvgne3a4a"
+	testMainnetDelegationID = "mdelg1qv9pzxqlyckngw6zf9g9whn9d3eh4qvg3d8nn4"
+	testTestnetPoolID       = "tpool1qv9pzxqlyckngw6zf9g9whn9d3eh4qvgjzejcm"
+	testTestnetDelegationID = "tdelg1qv9pzxqlyckngw6zf9g9whn9d3eh4qvgsk0u7n"
+	testTestnetAddress      = "tmt1qv9pzxqlyckngw6zf9g9whn9d3eh4qvgh6gemw"
+	testMainnetTokenID      = "mmltk1qv9pzxqlyckngw6zf9g9whn9d3eh4qvg6x9zrn"
+	testMainnetTokenID2     = "mmltk1pc83qygjzv2p29shrqv35xcur50p7gpp4x85fj"
+)
+
+func TestNetworksDetectByHRP(t *testing.T) {
+	networks := defaultNetworks()
+
+	tests := []struct {
+		name    string
+		id      string
+		network string
+	}{
+		{"mainnet pool", testMainnetPoolID, mainnetNetwork},
+		{"testnet pool", testTestnetPoolID, testnetNetwork},
+		{"testnet delegation", testTestnetDelegationID, testnetNetwork},
+		{"testnet address", testTestnetAddress, testnetNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network, err := networks.Detect(tt.id)
+			if err != nil || network.Name != tt.network {
+				t.Fatalf("expected %s, got %q, %v", tt.network, network.Name, err)
+			}
+		})
+	}
+
+	_, data, _ := bech32.Decode(testMainnetPoolID)
+	unknownID, _ := bech32.EncodeM("xpool", data)
+	if _, err := networks.Detect(unknownID); err == nil || !strings.HasPrefix(err.Error(), "this is not an ID of a known network") {
+		t.Fatalf("unexpected error for an unknown prefix: %v", err)
+	}
+}
+
+func TestBuildNetworks(t *testing.T) {
+	networks, err := buildNetworks([]string{"http://mainnet.local"}, []NetworkConfig{
+		{Name: testnetNetwork, APIBaseURL: URLList{"http://testnet.local"}},
+		{Name: "regtest", APIBaseURL: URLList{"http://regtest.local"}, PoolHRP: "rpool", DelegationHRP: "rdelg", AddressHRP: "rmt", TokenHRP: "rmltk"},
+	})
+	if err != nil {
+		t.Fatalf("buildNetworks failed: %v", err)
+	}
+	if networks.Primary().APIBaseURLs[0] != "http://mainnet.local" {
+		t.Fatalf("unexpected primary network %+v", networks.Primary())
+	}
+	if testnet, _ := networks.Lookup(testnetNetwork); testnet.APIBaseURLs[0] != "http://testnet.local" || testnet.PoolHRP != "tpool" {
+		t.Fatalf("unexpected testnet override %+v", testnet)
+	}
+	if _, ok := networks.Lookup("regtest"); !ok {
+		t.Fatal("expected the custom network to be added")
+	}
+
+	if _, err := buildNetworks(nil, []NetworkConfig{{Name: "devnet", APIBaseURL: URLList{"http://devnet.local"}}}); err == nil {
+		t.Fatal("expected an error for a custom network without HRPs")
+	}
+}
+
+func TestNetworkClientRoutesByHRP(t *testing.T) {
+	serve := func(balance string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"staker_balance":{"atoms":"` + balance + `"}}`))
+		}))
+	}
+	mainnetServer := serve("100000000000")
+	defer mainnetServer.Close()
+	testnetServer := serve("200000000000")
+	defer testnetServer.Close()
+
+	networks, err := buildNetworks([]string{mainnetServer.URL}, []NetworkConfig{{Name: testnetNetwork, APIBaseURL: URLList{testnetServer.URL}}})
+	if err != nil {
+		t.Fatalf("buildNetworks failed: %v", err)
+	}
+	client := NewNetworkClient(networks)
+
+	for id, expected := range map[string]Amount{
+		testMainnetPoolID: AmountFromML(1),
+		testTestnetPoolID: AmountFromML(2),
+	} {
+		status, err := client.GetPoolStatus(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetPoolStatus(%s) failed: %v", id, err)
+		}
+		if status.Balance.Cmp(expected) != 0 {
+			t.Fatalf("%s: expected %s, got %s", id, expected, status.Balance)
+		}
+	}
+}
+
+func TestAddPoolStoresNetwork(t *testing.T) {
+	store, _ := newTestSQLStore(t)
+	ctx := context.Background()
+	if err := store.AddPool(ctx, "u1", testTestnetPoolID, testnetNetwork); err != nil {
+		t.Fatalf("AddPool failed: %v", err)
+	}
+	network, err := store.GetTrackedNetwork(ctx, testTestnetPoolID)
+	if err != nil || network != testnetNetwork {
+		t.Fatalf("expected %s, got %q, %v", testnetNetwork, network, err)
+	}
+	if _, err := store.GetTrackedNetwork(ctx, testMainnetPoolID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for an untracked pool, got %v", err)
+	}
+}
+
+func TestNetworkClientRoutesTrackedIDsByStoredNetwork(t *testing.T) {
+	mainnetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"staker_balance":{"atoms":"100000000000"}}`))
+	}))
+	defer mainnetServer.Close()
+	testnetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"staker_balance":{"atoms":"200000000000"}}`))
+	}))
+	defer testnetServer.Close()
+	networks, err := buildNetworks([]string{mainnetServer.URL}, []NetworkConfig{{Name: testnetNetwork, APIBaseURL: URLList{testnetServer.URL}}})
+	if err != nil {
+		t.Fatalf("buildNetworks failed: %v", err)
+	}
+
+	store, exec := newTestSQLStore(t)
+	ctx := context.Background()
+	// A pool stored as testnet is looked up there whatever its prefix says.
+	exec("INSERT INTO pools (userID, poolID, network) VALUES ('u1', ?, 'testnet')", testMainnetPoolID)
+	client := NewNetworkClient(networks)
+	client.SetTrackedNetworks(store.GetTrackedNetwork)
+	status, err := client.GetPoolStatus(ctx, testMainnetPoolID)
+	if err != nil || status.Balance.Cmp(AmountFromML(2)) != 0 {
+		t.Fatalf("expected the testnet balance, got %+v, %v", status, err)
+	}
+
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.networks = networks
+	if label := app.networkLabel(ctx, testMainnetPoolID); label != " (testnet)" {
+		t.Fatalf("unexpected label %q", label)
+	}
+	if label := app.networkLabel(ctx, testMainnetDelegationID); label != "" {
+		t.Fatalf("expected no label for the primary network, got %q", label)
+	}
+}
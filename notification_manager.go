@@ -8,13 +8,19 @@ import (
 
 type NotificationManager struct {
 	mu      sync.Mutex
-	entries map[string]context.CancelFunc
+	entries map[string]*notificationEntry
 	wg      sync.WaitGroup
 }
 
+// notificationEntry is compared by pointer so a routine that exits after
+// being replaced cannot remove its successor.
+type notificationEntry struct {
+	cancel context.CancelFunc
+}
+
 func NewNotificationManager() *NotificationManager {
 	return &NotificationManager{
-		entries: make(map[string]context.CancelFunc),
+		entries: make(map[string]*notificationEntry),
 	}
 }
 
@@ -26,7 +32,8 @@ func (m *NotificationManager) Start(ctx context.Context, userID string, start fu
 		return false
 	}
 	ctxIn, cancel := context.WithCancel(ctx)
-	m.entries[userID] = cancel
+	entry := &notificationEntry{cancel: cancel}
+	m.entries[userID] = entry
 	m.wg.Add(1)
 	m.mu.Unlock()
 	log.Printf("Starting notification for user %s", userID)
@@ -34,8 +41,11 @@ func (m *NotificationManager) Start(ctx context.Context, userID string, start fu
 	go func() {
 		defer m.wg.Done()
 		start(ctxIn)
+		cancel()
 		m.mu.Lock()
-		delete(m.entries, userID)
+		if m.entries[userID] == entry {
+			delete(m.entries, userID)
+		}
 		m.mu.Unlock()
 		log.Printf("Notification routine exited for user %s", userID)
 	}()
@@ -51,14 +61,14 @@ func (m *NotificationManager) Active(userID string) bool {
 
 func (m *NotificationManager) Stop(userID string) bool {
 	m.mu.Lock()
-	cancel, exists := m.entries[userID]
+	entry, exists := m.entries[userID]
 	if exists {
 		delete(m.entries, userID)
 	}
 	m.mu.Unlock()
 	if exists {
 		log.Printf("Stopping notification for user %s", userID)
-		cancel()
+		entry.cancel()
 	}
 	return exists
 }
@@ -66,10 +76,10 @@ func (m *NotificationManager) Stop(userID string) bool {
 func (m *NotificationManager) StopAll() {
 	m.mu.Lock()
 	cancels := make([]context.CancelFunc, 0, len(m.entries))
-	for _, cancel := range m.entries {
-		cancels = append(cancels, cancel)
+	for _, entry := range m.entries {
+		cancels = append(cancels, entry.cancel)
 	}
-	m.entries = make(map[string]context.CancelFunc)
+	m.entries = make(map[string]*notificationEntry)
 	m.mu.Unlock()
 
 	if len(cancels) > 0 {
@@ -74,3 +74,28 @@ func TestNotificationManagerStartDuplicate(t *testing.T) {
 
 	manager.StopAll()
 }
+
+func TestNotificationManagerRestartKeepsNewRoutine(t *testing.T) {
+	manager := NewNotificationManager()
+
+	release := make(chan struct{})
+	manager.Start(context.Background(), "user-1", func(ctx context.Context) {
+		<-ctx.Done()
+		<-release
+	})
+	manager.Stop("user-1")
+	if !manager.Start(context.Background(), "user-1", func(ctx context.Context) {
+		<-ctx.Done()
+	}) {
+		t.Fatal("expected restart after stop to succeed")
+	}
+
+	// The first routine exits only now, after it was replaced.
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	if !manager.Active("user-1") {
+		t.Fatal("expected the restarted routine to stay registered")
+	}
+
+	manager.StopAll()
+}
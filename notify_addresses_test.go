@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-telegram/bot"
+)
+
+func TestNotifyAddressesHonoursThreshold(t *testing.T) {
+	store, _ := newTestSQLStore(t)
+	ctx := context.Background()
+	if err := store.AddMonitoredAddress(ctx, "u1", "a1", mainnetNetwork, 0, true, 1); err != nil {
+		t.Fatalf("AddMonitoredAddress failed: %v", err)
+	}
+	if err := store.AddMonitoredAddress(ctx, "u1", "a2", mainnetNetwork, 100, false, 1); err != nil {
+		t.Fatalf("AddMonitoredAddress failed: %v", err)
+	}
+
+	client := &fakeBalanceClient{addressBalances: map[string]Amount{
+		"a1": AmountFromAtoms(1),
+		"a2": AmountFromML(60),
+	}}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.notifyAddressesBalanceChanges(ctx, "u1", 1, 0)
+	if len(messages) != 1 || messages[0] != "`a1`: \\+0.00000000001 ML" {
+		t.Fatalf("expected only the notify-on-change address, got %v", messages)
+	}
+
+	messages = nil
+	client.addressBalances["a2"] = AmountFromML(120)
+	app.notifyAddressesBalanceChanges(ctx, "u1", 1, 0)
+	if len(messages) != 1 || messages[0] != "`a2`: \\+120 ML" {
+		t.Fatalf("expected accumulated change over the threshold, got %v", messages)
+	}
+
+	messages = nil
+	app.notifyAddressesBalanceChanges(ctx, "u1", 1, 0)
+	if len(messages) != 0 {
+		t.Fatalf("expected no messages without changes, got %v", messages)
+	}
+}
+
+func TestNotifyAddressesAnnouncesTokenChanges(t *testing.T) {
+	store, _ := newTestSQLStore(t)
+	ctx := context.Background()
+	if err := store.AddMonitoredAddress(ctx, "u1", "a1", mainnetNetwork, 0, true, 1); err != nil {
+		t.Fatalf("AddMonitoredAddress failed: %v", err)
+	}
+
+	client := &fakeBalanceClient{
+		addressBalances: map[string]Amount{"a1": {}},
+		addressTokens:   map[string]map[string]Amount{"a1": {testMainnetTokenID: AmountFromAtoms(250)}},
+		tokenInfos:      map[string]TokenInfo{testMainnetTokenID: {TokenID: testMainnetTokenID, Ticker: "USDX", Decimals: 2}},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.notifyAddressesBalanceChanges(ctx, "u1", 1, 0)
+	if len(messages) != 1 || messages[0] != "`a1`: \\+2.5 USDX" {
+		t.Fatalf("expected token receipt, got %v", messages)
+	}
+
+	messages = nil
+	client.addressTokens["a1"] = map[string]Amount{}
+	app.notifyAddressesBalanceChanges(ctx, "u1", 1, 0)
+	if len(messages) != 1 || messages[0] != "`a1`: \\-2.5 USDX" {
+		t.Fatalf("expected token spend, got %v", messages)
+	}
+
+	messages = nil
+	app.notifyAddressesBalanceChanges(ctx, "u1", 1, 0)
+	if len(messages) != 0 {
+		t.Fatalf("expected no messages without changes, got %v", messages)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-telegram/bot"
+)
+
+func newTestSQLStore(t *testing.T) (*SQLStore, func(query string, args ...any)) {
+	t.Helper()
+	db, err := initDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("initDB failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	store, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatalf("NewSQLStore failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("exec %q failed: %v", query, err)
+		}
+	}
+	return store, exec
+}
+
+func TestNotifyPoolsDecommissionedOnce(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO pools (userID, poolID, balance_atoms, state) VALUES ('u1', 'p1', ?, 'active')", AmountFromML(50000).String())
+
+	client := &fakeBalanceClient{poolStates: map[string]PoolState{"p1": PoolStateDecommissioned}}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.notifyPoolsBalanceChanges(context.Background(), "u1", 1, 0)
+	app.notifyPoolsBalanceChanges(context.Background(), "u1", 1, 0)
+
+	if len(messages) != 1 {
+		t.Fatalf("expected exactly one message, got %v", messages)
+	}
+	if expected := "`p1`: `decommissioned` (\\-50,000 ML)"; messages[0] != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, messages[0])
+	}
+	state, err := store.GetPoolState(context.Background(), "u1", "p1")
+	if err != nil {
+		t.Fatalf("GetPoolState failed: %v", err)
+	}
+	if state != PoolStateDecommissioned {
+		t.Fatalf("expected decommissioned state, got %v", state)
+	}
+}
+
+func TestNotifyPoolsIgnoresLookupErrors(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO pools (userID, poolID, balance_atoms, state) VALUES ('u1', 'p1', ?, 'active')", AmountFromML(10).String())
+
+	client := &fakeBalanceClient{poolErrors: map[string]error{
+		"p1": &APIError{Kind: ErrServer, URL: "p1", StatusCode: 503},
+	}}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.notifyPoolsBalanceChanges(context.Background(), "u1", 1, 0)
+	if len(messages) != 0 {
+		t.Fatalf("expected no messages on API failure, got %v", messages)
+	}
+	balance, err := store.GetPoolBalance(context.Background(), "u1", "p1")
+	if err != nil {
+		t.Fatalf("GetPoolBalance failed: %v", err)
+	}
+	if balance.Cmp(AmountFromML(10)) != 0 {
+		t.Fatalf("stored balance changed on failure: %s", balance)
+	}
+}
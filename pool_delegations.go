@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sort"
+)
+
+// topDelegatorsShown is how many of a pool's largest delegations
+// /pool_delegations lists.
+const topDelegatorsShown = 10
+
+// sortDelegationsByBalance orders delegations largest first, by ID between
+// equal balances.
+func sortDelegationsByBalance(delegations []DelegationInfo) {
+	sort.Slice(delegations, func(i, j int) bool {
+		if c := delegations[i].Balance.Cmp(delegations[j].Balance); c != 0 {
+			return c > 0
+		}
+		return delegations[i].DelegationID < delegations[j].DelegationID
+	})
+}
+
+// fundedDelegations drops delegations without balance: ones created but not
+// yet funded, or fully withdrawn, which the API server keeps listing.
+func fundedDelegations(delegations []DelegationInfo) []DelegationInfo {
+	funded := make([]DelegationInfo, 0, len(delegations))
+	for _, delegation := range delegations {
+		if delegation.Balance.Sign() > 0 {
+			funded = append(funded, delegation)
+		}
+	}
+	return funded
+}
+
+func delegationBalances(delegations []DelegationInfo) map[string]Amount {
+	balances := make(map[string]Amount, len(delegations))
+	for _, delegation := range delegations {
+		balances[delegation.DelegationID] = delegation.Balance
+	}
+	return balances
+}
+
+func sameBalances(a, b map[string]Amount) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, balance := range a {
+		if other, ok := b[id]; !ok || other.Cmp(balance) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// delegationChange is a delegation alert: either a delegation that received
+// its first coins, or a withdrawal from one.
+type delegationChange struct {
+	DelegationID string
+	New          bool
+	// Amount is the new delegation's balance or the amount withdrawn.
+	Amount Amount
+}
+
+// poolDelegationChanges compares a pool's delegations with the last seen
+// balances. A delegation is new when it had no balance before; withdrawals
+// smaller than threshold are not reported. Changes are in delegation ID
+// order.
+func poolDelegationChanges(lastSeen map[string]Amount, current []DelegationInfo, threshold Amount) []delegationChange {
+	balances := delegationBalances(current)
+	ids := make([]string, 0, len(balances)+len(lastSeen))
+	for id := range balances {
+		ids = append(ids, id)
+	}
+	for id := range lastSeen {
+		if _, ok := balances[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var changes []delegationChange
+	for _, id := range ids {
+		before, after := lastSeen[id], balances[id]
+		switch {
+		case before.Sign() <= 0 && after.Sign() > 0:
+			changes = append(changes, delegationChange{DelegationID: id, New: true, Amount: after})
+		case after.Cmp(before) < 0:
+			if withdrawn := before.Sub(after); withdrawn.Cmp(threshold) >= 0 {
+				changes = append(changes, delegationChange{DelegationID: id, Amount: withdrawn})
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"mintlayer_bot/fakeapi"
+)
+
+func TestGetPoolDelegationsPagesThroughFakeAPI(t *testing.T) {
+	server := fakeapi.NewServer()
+	defer server.Close()
+	server.SetPool("p1", fakeapi.Pool{StakerBalance: AmountFromML(40000).String()})
+	const count = listPageSize + 20
+	for i := 0; i < count; i++ {
+		server.SetDelegation(fmt.Sprintf("d%03d", i), fakeapi.Delegation{PoolID: "p1", Balance: AmountFromML(int64(i)).String()})
+	}
+	server.SetDelegation("other", fakeapi.Delegation{PoolID: "p2", Balance: "1"})
+
+	delegations, err := NewHTTPBalanceClient(server.URL).GetPoolDelegations(context.Background(), "p1")
+	if err != nil {
+		t.Fatalf("GetPoolDelegations failed: %v", err)
+	}
+	if len(delegations) != count {
+		t.Fatalf("expected %d delegations, got %d", count, len(delegations))
+	}
+	last := delegations[count-1]
+	if last.DelegationID != "d119" || last.PoolID != "p1" || last.Balance.Cmp(AmountFromML(119)) != 0 {
+		t.Fatalf("unexpected delegation %+v", last)
+	}
+}
+
+func TestPoolDelegationChanges(t *testing.T) {
+	lastSeen := map[string]Amount{
+		"d1": AmountFromML(100),
+		"d2": AmountFromML(1000),
+		"d3": AmountFromML(500),
+		"d4": {},
+	}
+	current := []DelegationInfo{
+		{DelegationID: "d1", Balance: AmountFromML(95)},
+		{DelegationID: "d2", Balance: AmountFromML(200)},
+		{DelegationID: "d4", Balance: AmountFromML(10)},
+		{DelegationID: "d5", Balance: AmountFromML(30)},
+		{DelegationID: "d6"},
+	}
+
+	changes := poolDelegationChanges(lastSeen, current, AmountFromML(50))
+	expected := []delegationChange{
+		{DelegationID: "d2", Amount: AmountFromML(800)},
+		{DelegationID: "d3", Amount: AmountFromML(500)},
+		{DelegationID: "d4", New: true, Amount: AmountFromML(10)},
+		{DelegationID: "d5", New: true, Amount: AmountFromML(30)},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i].DelegationID != expected[i].DelegationID || changes[i].New != expected[i].New || changes[i].Amount.Cmp(expected[i].Amount) != 0 {
+			t.Fatalf("change %d: expected %+v, got %+v", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestPoolDelegationsHandlerAndAlerts(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	ctx := context.Background()
+	poolID := testMainnetPoolID
+	exec("INSERT INTO pools (userID, poolID, balance_atoms, state) VALUES ('7', ?, '0', 'active')", poolID)
+
+	delegations := []DelegationInfo{{DelegationID: "d0"}}
+	for i := 1; i <= topDelegatorsShown+2; i++ {
+		delegations = append(delegations, DelegationInfo{DelegationID: fmt.Sprintf("d%02d", i), Balance: AmountFromML(int64(i * 100))})
+	}
+	client := &fakeBalanceClient{poolDelegations: map[string][]DelegationInfo{poolID: delegations}}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	command := func(text string) string {
+		t.Helper()
+		messages = nil
+		app.poolDelegationsHandler(ctx, nil, &models.Update{
+			Message: &models.Message{
+				Text: text,
+				Chat: models.Chat{ID: 5},
+				From: &models.User{ID: 7},
+			},
+		})
+		return strings.Join(messages, "\n")
+	}
+
+	got := command("/pool_delegations " + poolID)
+	expected := "Pool `" + poolID + "`: `12` delegations, total `7,800 ML`\nTop delegators:\n" +
+		"`d12`: 1,200 ML\n`d11`: 1,100 ML\n`d10`: 1,000 ML\n`d09`: 900 ML\n`d08`: 800 ML\n" +
+		"`d07`: 700 ML\n`d06`: 600 ML\n`d05`: 500 ML\n`d04`: 400 ML\n`d03`: 300 ML\nand 2 more\n"
+	if got != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, got)
+	}
+
+	if got := command("/pool_delegations " + testMainnetDelegationID + " notify 100"); got != "Invalid pool ID: this is a mainnet delegation ID, not a mainnet or testnet pool ID" {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	if got := command("/pool_delegations " + poolID + " notify 500"); !strings.HasPrefix(got, "You will be notified of new delegations") {
+		t.Fatalf("unexpected reply %q", got)
+	}
+
+	messages = nil
+	app.notifyPoolDelegations(ctx, "7", 5, 0)
+	if len(messages) != 0 {
+		t.Fatalf("expected no alerts before any change, got %q", messages)
+	}
+
+	changed := append([]DelegationInfo(nil), delegations...)
+	changed[0].Balance = AmountFromML(50)
+	changed[12].Balance = AmountFromML(1000)
+	changed[11].Balance = AmountFromML(500)
+	client.poolDelegations[poolID] = changed
+	app.notifyPoolDelegations(ctx, "7", 5, 0)
+	expectedAlerts := []string{
+		"`" + poolID + "`: new delegation `d0` of 50 ML",
+		"`" + poolID + "`: `d11` withdrew 600 ML",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expectedAlerts, "\n") {
+		t.Fatalf("unexpected alerts:\nexpected: %q\ngot:      %q", expectedAlerts, messages)
+	}
+
+	messages = nil
+	app.notifyPoolDelegations(ctx, "7", 5, 0)
+	if len(messages) != 0 {
+		t.Fatalf("expected alerts not to repeat, got %q", messages)
+	}
+
+	command("/pool_delegations " + poolID + " notify off")
+	if pools, err := store.GetDelegationAlertPools(ctx, "7"); err != nil || len(pools) != 0 {
+		t.Fatalf("expected alerts to be off, got %v, %v", pools, err)
+	}
+}
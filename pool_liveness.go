@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// targetBlockSpacing is the chain's target time between two blocks.
+	targetBlockSpacing = 2 * time.Minute
+	// livenessMissChance is how unlikely a gap must be for a pool of its
+	// stake share before it is alerted on.
+	livenessMissChance = 0.01
+	// minLivenessWindow keeps pools with a large stake share from alerting
+	// on ordinary gaps of a few blocks.
+	minLivenessWindow = time.Hour
+)
+
+// PoolLiveness is the per-user liveness watch on a pool. Window is the
+// user's override, zero to expect blocks at the pool's stake share. Height is
+// the last block scanned for the pool's blocks and LastBlockTime the
+// timestamp of the last one it produced, or of the block the watch started
+// at if none was seen since.
+type PoolLiveness struct {
+	PoolID        string
+	Window        time.Duration
+	Height        int64
+	LastBlockTime time.Time
+	Alerted       bool
+}
+
+// expectedBlockInterval is the mean time between blocks of a pool holding
+// share of the network's stake.
+func expectedBlockInterval(share float64) time.Duration {
+	return time.Duration(float64(targetBlockSpacing) / share)
+}
+
+// livenessWindowFor is the gap after which a pool expected to produce a block
+// every interval is alerted on. Blocks arrive as a Poisson process, so a gap
+// longer than interval*ln(1/livenessMissChance) has at most that chance.
+func livenessWindowFor(interval time.Duration) time.Duration {
+	window := time.Duration(float64(interval) * math.Log(1/livenessMissChance))
+	return max(window, minLivenessWindow)
+}
+
+// latestPoolBlock returns the newest of blocks produced by the pool after its
+// last known block.
+func (p PoolLiveness) latestPoolBlock(blocks []BlockInfo) (BlockInfo, bool) {
+	var latest BlockInfo
+	found := false
+	for _, block := range blocks {
+		if block.PoolID == p.PoolID && block.Timestamp.After(p.LastBlockTime) {
+			latest = block
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// formatGap renders a duration in whole hours and minutes, e.g. "6h 5m".
+func formatGap(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	hours := int64(d / time.Hour)
+	minutes := int64((d % time.Hour) / time.Minute)
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+}
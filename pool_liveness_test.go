@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+func TestNotifyPoolLivenessAlertsAndRecovers(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO pools (userID, poolID) VALUES ('u1', 'p1')")
+	ctx := context.Background()
+	start := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	if err := store.SetPoolLiveness(ctx, "u1", "p1", true, 6*time.Hour, BlockInfo{Timestamp: start}); err != nil {
+		t.Fatalf("SetPoolLiveness failed: %v", err)
+	}
+
+	client := &fakeBalanceClient{}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.tip = NewChainTipWatcher(client, time.Hour)
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.tip.addBlock(BlockInfo{Height: 10, PoolID: "p2", Timestamp: start.Add(5 * time.Hour)})
+	app.notifyPoolLiveness(ctx, "u1", 1)
+	if len(messages) != 0 {
+		t.Fatalf("expected no alert inside the window, got %v", messages)
+	}
+
+	app.tip.addBlock(BlockInfo{Height: 11, PoolID: "p2", Timestamp: start.Add(6*time.Hour + 5*time.Minute)})
+	app.notifyPoolLiveness(ctx, "u1", 1)
+	app.notifyPoolLiveness(ctx, "u1", 1)
+	if len(messages) != 1 || messages[0] != "`p1`: no block produced for 6h 5m (alert window 6h)" {
+		t.Fatalf("expected a single alert, got %v", messages)
+	}
+
+	messages = nil
+	app.tip.addBlock(BlockInfo{Height: 12, PoolID: "p1", Timestamp: start.Add(7 * time.Hour)})
+	app.notifyPoolLiveness(ctx, "u1", 1)
+	app.notifyPoolLiveness(ctx, "u1", 1)
+	if len(messages) != 1 || messages[0] != "`p1`: producing blocks again at block `12` after 7h without blocks" {
+		t.Fatalf("expected a single recovery message, got %v", messages)
+	}
+
+	pools, err := store.GetPoolLiveness(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetPoolLiveness failed: %v", err)
+	}
+	if len(pools) != 1 || pools[0].Alerted || !pools[0].LastBlockTime.Equal(start.Add(7*time.Hour)) {
+		t.Fatalf("unexpected stored liveness %+v", pools)
+	}
+}
+
+func TestNotifyPoolLivenessExpectsBlocksAtStakeShare(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO pools (userID, poolID) VALUES ('u1', 'p1')")
+	ctx := context.Background()
+	start := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	if err := store.SetPoolLiveness(ctx, "u1", "p1", true, 0, BlockInfo{Height: 10, Timestamp: start}); err != nil {
+		t.Fatalf("SetPoolLiveness failed: %v", err)
+	}
+
+	// With 5% of the stake p1 is expected to produce a block every 40
+	// minutes, so the alert comes after about three hours without one.
+	client := &fakeBalanceClient{
+		poolInfos:  map[string]PoolInfo{"p1": {PoolID: "p1", State: PoolStateActive, StakerBalance: AmountFromML(50000)}},
+		totalStake: AmountFromML(1000000),
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.tip = NewChainTipWatcher(client, time.Hour)
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.tip.addBlock(BlockInfo{Height: 11, PoolID: "p2", Timestamp: start.Add(3 * time.Hour)})
+	app.notifyPoolLiveness(ctx, "u1", 1)
+	if len(messages) != 0 {
+		t.Fatalf("expected no alert before the expected window, got %v", messages)
+	}
+	app.tip.addBlock(BlockInfo{Height: 12, PoolID: "p2", Timestamp: start.Add(3*time.Hour + 10*time.Minute)})
+	app.notifyPoolLiveness(ctx, "u1", 1)
+	expected := "`p1`: no block produced for 3h 10m, one is expected every 40m at its stake share"
+	if len(messages) != 1 || messages[0] != expected {
+		t.Fatalf("unexpected alert %v", messages)
+	}
+}
+
+func TestNotifyPoolLivenessCatchesUpAfterRestart(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	exec("INSERT INTO pools (userID, poolID) VALUES ('u1', 'p1')")
+	ctx := context.Background()
+	start := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	if err := store.SetPoolLiveness(ctx, "u1", "p1", true, 6*time.Hour, BlockInfo{Height: 10, Timestamp: start}); err != nil {
+		t.Fatalf("SetPoolLiveness failed: %v", err)
+	}
+
+	// p1 produced block 20 while the bot was down; the watcher only holds
+	// the blocks after it.
+	client := &fakeBalanceClient{tipHeight: 100, blocks: map[int64]BlockInfo{}}
+	for h := int64(1); h <= 100; h++ {
+		client.blocks[h] = BlockInfo{Height: h, PoolID: "p2", Timestamp: start.Add(time.Duration(h) * 6 * time.Minute)}
+	}
+	client.blocks[20] = BlockInfo{Height: 20, PoolID: "p1", Timestamp: start.Add(2 * time.Hour)}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.tip = NewChainTipWatcher(client, time.Hour)
+	app.tip.poll(ctx)
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+
+	app.notifyPoolLiveness(ctx, "u1", 1)
+	if len(messages) != 0 {
+		t.Fatalf("expected the block produced during the downtime to count, got %v", messages)
+	}
+	pools, err := store.GetPoolLiveness(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetPoolLiveness failed: %v", err)
+	}
+	if len(pools) != 1 || pools[0].Height != 100 || !pools[0].LastBlockTime.Equal(start.Add(2*time.Hour)) {
+		t.Fatalf("unexpected stored liveness %+v", pools)
+	}
+}
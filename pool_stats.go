@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// poolSampleInterval is the minimum time between two stored balance
+	// samples of a pool.
+	poolSampleInterval = time.Hour
+	// poolSampleRetention is how long samples are kept.
+	poolSampleRetention = 30 * 24 * time.Hour
+	// poolStatsWindow is how much history reward estimates look at.
+	poolStatsWindow = 7 * 24 * time.Hour
+	// minPoolStatsSpan is the least history an estimate needs.
+	minPoolStatsSpan = time.Hour
+)
+
+// defaultBlockReward is the block subsidy assumed when no recent block is
+// known to read the reward from.
+var defaultBlockReward = AmountFromML(202)
+
+// PoolSample is a stored observation of a pool's balances. Samples are kept
+// per pool, not per user.
+type PoolSample struct {
+	PoolID             string
+	ObservedAt         time.Time
+	Height             int64
+	StakerBalance      Amount
+	DelegationsBalance Amount
+}
+
+// PoolRewardEstimate is what a pool earns according to its balance history.
+// Yields are annualised with daily compounding, since rewards are added to
+// the staked balances.
+type PoolRewardEstimate struct {
+	// Span is the length of history the estimate is based on.
+	Span         time.Duration
+	BlocksPerDay float64
+	// RewardPerDay is the whole pool's reward in ML.
+	RewardPerDay float64
+	PoolAPY      float64
+	// DelegatorAPY is the yield of delegated coins after the pool's cost
+	// per block and margin.
+	DelegatorAPY float64
+	// delegatorDailyRate is the daily reward per delegated ML.
+	delegatorDailyRate float64
+}
+
+// estimatePoolRewards derives the pool's block rate from the growth of the
+// staker balance, which only changes through rewards, unlike the delegations
+// balance that also moves with deposits and withdrawals. Per block the pool
+// owner gets the cost per block, the margin of the rest, and the pledge's
+// share of what remains; delegators share the remainder by balance. info
+// gives the current cost, margin and balances, blockReward the reward of one
+// block. samples must be in time order; history before a decommission or
+// other drop of the staker balance is ignored.
+func estimatePoolRewards(samples []PoolSample, info PoolInfo, blockReward Amount) (PoolRewardEstimate, bool) {
+	start := 0
+	for i := 1; i < len(samples); i++ {
+		if samples[i].StakerBalance.Cmp(samples[i-1].StakerBalance) < 0 {
+			start = i
+		}
+	}
+	if len(samples)-start < 2 {
+		return PoolRewardEstimate{}, false
+	}
+	first, last := samples[start], samples[len(samples)-1]
+	span := last.ObservedAt.Sub(first.ObservedAt)
+	if span < minPoolStatsSpan {
+		return PoolRewardEstimate{}, false
+	}
+	total := info.TotalStake().MLFloat()
+	if total <= 0 {
+		return PoolRewardEstimate{}, false
+	}
+
+	reward := blockReward.MLFloat()
+	cost := math.Min(info.CostPerBlock.MLFloat(), reward)
+	margin := float64(info.MarginRatioPerThousand) / 1000
+	shared := (reward - cost) * (1 - margin)
+	ownerPerBlock := cost + (reward-cost)*margin + shared*info.StakerBalance.MLFloat()/total
+
+	days := span.Hours() / 24
+	growthPerDay := last.StakerBalance.Sub(first.StakerBalance).MLFloat() / days
+	estimate := PoolRewardEstimate{Span: span}
+	if ownerPerBlock > 0 {
+		estimate.BlocksPerDay = growthPerDay / ownerPerBlock
+	}
+	estimate.RewardPerDay = estimate.BlocksPerDay * reward
+	estimate.PoolAPY = annualise(estimate.RewardPerDay / total)
+	estimate.delegatorDailyRate = estimate.BlocksPerDay * shared / total
+	estimate.DelegatorAPY = annualise(estimate.delegatorDailyRate)
+	return estimate, true
+}
+
+// DelegationRewardPerDay is the expected daily reward of a delegation of
+// balance to the pool.
+func (e PoolRewardEstimate) DelegationRewardPerDay(balance Amount) Amount {
+	return AmountFromMLFloat(balance.MLFloat() * e.delegatorDailyRate)
+}
+
+func annualise(dailyRate float64) float64 {
+	return math.Pow(1+dailyRate, 365) - 1
+}
+
+// formatYield renders a yield fraction as a percentage, e.g. 0.0421 as
+// "4.21%".
+func formatYield(yield float64) string {
+	return fmt.Sprintf("%.2f%%", yield*100)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func testPoolInfo(poolID string) PoolInfo {
+	return PoolInfo{
+		PoolID:                 poolID,
+		State:                  PoolStateActive,
+		StakerBalance:          AmountFromML(40000),
+		DelegationsBalance:     AmountFromML(9960000),
+		MarginRatioPerThousand: 100,
+		CostPerBlock:           AmountFromML(50),
+	}
+}
+
+func TestEstimatePoolRewards(t *testing.T) {
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	info := testPoolInfo("p1")
+	// Per 202 ML block the owner gets 50 + 15.2 margin + 0.4% of 136.8, so
+	// ten blocks a day grow the pledge by 657.472 ML.
+	samples := []PoolSample{
+		{ObservedAt: start.Add(-48 * time.Hour), StakerBalance: AmountFromML(90000)},
+		{ObservedAt: start, StakerBalance: AmountFromML(40000)},
+		{ObservedAt: start.Add(12 * time.Hour), StakerBalance: AmountFromML(40300)},
+		{ObservedAt: start.Add(24 * time.Hour), StakerBalance: AmountFromMLFloat(40657.472)},
+	}
+
+	estimate, ok := estimatePoolRewards(samples, info, AmountFromML(202))
+	if !ok {
+		t.Fatal("expected an estimate")
+	}
+	if estimate.Span != 24*time.Hour {
+		t.Fatalf("expected history before the decommission to be ignored, span %v", estimate.Span)
+	}
+	assertClose(t, "blocks per day", estimate.BlocksPerDay, 10)
+	assertClose(t, "reward per day", estimate.RewardPerDay, 2020)
+	assertClose(t, "pool APY", estimate.PoolAPY, math.Pow(1.000202, 365)-1)
+	assertClose(t, "delegator APY", estimate.DelegatorAPY, math.Pow(1.0001368, 365)-1)
+	assertClose(t, "delegation reward", estimate.DelegationRewardPerDay(AmountFromML(1000)).MLFloat(), 0.1368)
+
+	if _, ok := estimatePoolRewards(samples[:2], info, AmountFromML(202)); ok {
+		t.Fatal("expected no estimate from a single sample after the drop")
+	}
+	short := []PoolSample{samples[1], {ObservedAt: start.Add(30 * time.Minute), StakerBalance: AmountFromML(40100)}}
+	if _, ok := estimatePoolRewards(short, info, AmountFromML(202)); ok {
+		t.Fatal("expected no estimate from less than an hour of history")
+	}
+}
+
+func assertClose(t *testing.T, name string, got, expected float64) {
+	t.Helper()
+	if math.Abs(got-expected) > 1e-6*math.Max(1, math.Abs(expected)) {
+		t.Fatalf("%s: expected %v, got %v", name, expected, got)
+	}
+}
+
+func TestSQLStorePoolSamples(t *testing.T) {
+	store, _ := newTestSQLStore(t)
+	ctx := context.Background()
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	for i, offset := range []time.Duration{0, 30 * time.Minute, 61 * time.Minute, 3 * time.Hour} {
+		sample := PoolSample{PoolID: "p1", ObservedAt: start.Add(offset), Height: int64(i), StakerBalance: AmountFromML(int64(i))}
+		if err := store.AddPoolSample(ctx, sample, time.Hour); err != nil {
+			t.Fatalf("AddPoolSample failed: %v", err)
+		}
+	}
+	samples, err := store.GetPoolSamples(ctx, "p1", start)
+	if err != nil {
+		t.Fatalf("GetPoolSamples failed: %v", err)
+	}
+	if len(samples) != 3 || samples[0].Height != 0 || samples[1].Height != 2 || samples[2].Height != 3 {
+		t.Fatalf("expected samples at least an hour apart, got %+v", samples)
+	}
+	if !samples[1].ObservedAt.Equal(start.Add(61*time.Minute)) || samples[1].StakerBalance.Cmp(AmountFromML(2)) != 0 {
+		t.Fatalf("unexpected sample %+v", samples[1])
+	}
+
+	if err := store.PrunePoolSamples(ctx, start.Add(2*time.Hour)); err != nil {
+		t.Fatalf("PrunePoolSamples failed: %v", err)
+	}
+	if samples, _ := store.GetPoolSamples(ctx, "p1", time.Time{}); len(samples) != 1 {
+		t.Fatalf("expected one sample after pruning, got %+v", samples)
+	}
+}
+
+func TestPoolStatsHandler(t *testing.T) {
+	const (
+		poolID       = "mpool1qv9pzxqlyckngw6zf9g9whn9d3eh4qvgne3a4a"
+		delegationID = "mdelg1qv9pzxqlyckngw6zf9g9whn9d3eh4qvg3d8nn4"
+	)
+	store, exec := newTestSQLStore(t)
+	ctx := context.Background()
+	exec("INSERT INTO delegations (userID, delegationID, balance_atoms) VALUES ('7', ?, '0')", delegationID)
+	exec("INSERT INTO delegations (userID, delegationID, balance_atoms) VALUES ('7', 'd2', '0')")
+	// A bit over ten blocks a day for the last two days.
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	if err := store.AddPoolSample(ctx, PoolSample{PoolID: poolID, ObservedAt: now.Add(-48 * time.Hour), StakerBalance: AmountFromML(40000 - 2*660)}, time.Hour); err != nil {
+		t.Fatalf("AddPoolSample failed: %v", err)
+	}
+
+	client := &fakeBalanceClient{
+		poolInfos: map[string]PoolInfo{poolID: testPoolInfo(poolID)},
+		delegationInfos: map[string]DelegationInfo{
+			delegationID: {DelegationID: delegationID, PoolID: poolID, Balance: AmountFromML(1000)},
+			"d2":         {DelegationID: "d2", PoolID: "mpool1other", Balance: AmountFromML(5)},
+		},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.now = func() time.Time { return now }
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Text: "/pool_stats " + poolID,
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+	app.poolStatsHandler(ctx, nil, update)
+	expected := "Pool `" + poolID + "`, estimated from 48h of history:\n" +
+		"Blocks: ~10.0 per day\n" +
+		"Rewards: ~2,027.76 ML per day\n" +
+		"Pool APY: ~7.68%\n" +
+		"Delegator APY: ~5.14% after 10% margin and 50 ML cost per block\n" +
+		"Your delegations:\n" +
+		"`" + delegationID + "`: 1,000 ML, ~0.13 ML per day\n"
+	if lastMessage != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+}
+
+func TestSamplePoolsWithoutNotifications(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	ctx := context.Background()
+	exec("INSERT INTO pools (userID, poolID) VALUES ('u1', 'p1'), ('u2', 'p1'), ('u2', 'p2')")
+
+	client := &fakeBalanceClient{
+		poolInfos:  map[string]PoolInfo{"p1": testPoolInfo("p1")},
+		poolStates: map[string]PoolState{"p2": PoolStateDecommissioned},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	app.now = func() time.Time { return now }
+
+	app.samplePools(ctx)
+	app.samplePools(ctx)
+	if samples, _ := store.GetPoolSamples(ctx, "p1", time.Time{}); len(samples) != 1 {
+		t.Fatalf("expected one sample of the tracked pool, got %+v", samples)
+	}
+	if samples, _ := store.GetPoolSamples(ctx, "p2", time.Time{}); len(samples) != 0 {
+		t.Fatalf("expected no samples of a decommissioned pool, got %+v", samples)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import "fmt"
+
+// PoolState is the lifecycle state of a staking pool as seen by the API
+// server. PoolStateUnknown means the pool has not been looked up yet.
+type PoolState int
+
+const (
+	PoolStateUnknown PoolState = iota
+	PoolStateActive
+	PoolStateDecommissioned
+	PoolStateNotFound
+)
+
+func (s PoolState) String() string {
+	switch s {
+	case PoolStateActive:
+		return "active"
+	case PoolStateDecommissioned:
+		return "decommissioned"
+	case PoolStateNotFound:
+		return "not found"
+	default:
+		return "unknown"
+	}
+}
+
+// storageKey is the value kept in the pools.state column.
+func (s PoolState) storageKey() string {
+	switch s {
+	case PoolStateActive:
+		return "active"
+	case PoolStateDecommissioned:
+		return "decommissioned"
+	case PoolStateNotFound:
+		return "not_found"
+	default:
+		return ""
+	}
+}
+
+func parsePoolState(key string) (PoolState, error) {
+	switch key {
+	case "":
+		return PoolStateUnknown, nil
+	case "active":
+		return PoolStateActive, nil
+	case "decommissioned":
+		return PoolStateDecommissioned, nil
+	case "not_found":
+		return PoolStateNotFound, nil
+	default:
+		return PoolStateUnknown, fmt.Errorf("unknown pool state %q", key)
+	}
+}
+
+// PoolStatus is the result of a pool lookup. Balance is the staker balance
+// and is zero unless State is PoolStateActive.
+type PoolStatus struct {
+	State   PoolState
+	Balance Amount
+}
+
+// PoolInfo is the full pool record returned by /api/v2/pool/{id}. Only
+// PoolID and State are set when State is PoolStateNotFound.
+type PoolInfo struct {
+	PoolID                  string
+	State                   PoolState
+	StakerBalance           Amount
+	DelegationsBalance      Amount
+	MarginRatioPerThousand  int64
+	CostPerBlock            Amount
+	VRFPublicKey            string
+	StakerDestination       string
+	DecommissionDestination string
+	// Height is the tip of the API server that answered, read before the
+	// pool; 0 when unknown.
+	Height int64
+}
+
+func (p PoolInfo) Status() PoolStatus {
+	if p.State != PoolStateActive {
+		return PoolStatus{State: p.State}
+	}
+	return PoolStatus{State: p.State, Balance: p.StakerBalance}
+}
+
+// TotalStake is the pledge plus everything delegated to the pool.
+func (p PoolInfo) TotalStake() Amount {
+	return p.StakerBalance.Add(p.DelegationsBalance)
+}
+
+// formatPerThousand renders a per-thousand ratio as a percentage, e.g. 25 as
+// "2.5%".
+func formatPerThousand(perThousand int64) string {
+	if perThousand%10 == 0 {
+		return fmt.Sprintf("%d%%", perThousand/10)
+	}
+	return fmt.Sprintf("%d.%d%%", perThousand/10, perThousand%10)
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tidwall/gjson"
@@ -20,86 +25,569 @@ var httpClient = &http.Client{
 	Timeout: 10 * time.Second,
 }
 
-func getBlocks() (int64, error) {
-	return getBlocksWithBaseURL(defaultAPIBaseURL)
-}
-
-func getBlocksWithBaseURL(baseURL string) (int64, error) {
+func getBlocksWithBaseURL(ctx context.Context, client *http.Client, baseURL string) (int64, error) {
 	url := fmt.Sprintf("%s/api/v1/blocks", baseURL)
-	resp, err := getWithRetry(url, 3)
+	body, err := getJSON(ctx, client, url)
 	if err != nil {
 		return 0, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	blocks := gjson.GetBytes(body, "blocks")
+	if !blocks.Exists() {
+		return 0, malformedResponse(url, errors.New("missing blocks"))
+	}
+	return blocks.Int(), nil
+}
+
+// getBlockAtHeightWithBaseURL resolves the block on the main chain at height
+// and reads the producing pool, timestamp and reward outputs from it.
+func getBlockAtHeightWithBaseURL(ctx context.Context, client *http.Client, baseURL string, height int64) (BlockInfo, error) {
+	url := fmt.Sprintf("%s/api/v2/chain/%d", baseURL, height)
+	body, err := getJSON(ctx, client, url)
 	if err != nil {
-		return 0, err
+		return BlockInfo{}, err
+	}
+	blockID := gjson.ParseBytes(body).String()
+	if blockID == "" {
+		return BlockInfo{}, malformedResponse(url, errors.New("missing block id"))
 	}
 
-	blocks := gjson.GetBytes(body, "blocks").Int()
-	return blocks, nil
+	url = fmt.Sprintf("%s/api/v2/block/%s", baseURL, blockID)
+	body, err = getJSON(ctx, client, url)
+	if err != nil {
+		return BlockInfo{}, err
+	}
+	return parseBlockInfo(url, height, blockID, body)
+}
+
+// parseBlockInfo reads a block of /api/v2/block. A proof-of-stake block pays
+// its subsidy through a ProduceBlockFromStake output, which names the pool
+// but carries no amount: the coins are added to the pool's balance. The
+// reward is then left zero, meaning unknown; only outputs with a coin value
+// are summed.
+func parseBlockInfo(url string, height int64, blockID string, body []byte) (BlockInfo, error) {
+	timestamp := gjson.GetBytes(body, "header.timestamp.timestamp")
+	if !timestamp.Exists() {
+		timestamp = gjson.GetBytes(body, "header.timestamp")
+	}
+	if timestamp.Type != gjson.Number {
+		return BlockInfo{}, malformedResponse(url, errors.New("missing header.timestamp"))
+	}
+	poolID := gjson.GetBytes(body, "header.consensus_data.PoS.stake_pool_id")
+	if !poolID.Exists() {
+		poolID = gjson.GetBytes(body, "header.consensus_data.pool_id")
+	}
+	if !poolID.Exists() {
+		poolID = gjson.GetBytes(body, `body.reward.#(type=="ProduceBlockFromStake").pool_id`)
+	}
+
+	info := BlockInfo{
+		Height:    height,
+		ID:        blockID,
+		Timestamp: time.Unix(timestamp.Int(), 0).UTC(),
+		PoolID:    poolID.String(),
+	}
+	for _, output := range gjson.GetBytes(body, "body.reward").Array() {
+		atoms := output.Get("value.amount.atoms")
+		if !atoms.Exists() {
+			continue
+		}
+		amount, err := ParseAmountAtoms(atoms.String())
+		if err != nil {
+			return BlockInfo{}, malformedResponse(url, err)
+		}
+		info.Reward = info.Reward.Add(amount)
+	}
+	return info, nil
 }
 
-func getPoolBalance(poolID string) (int64, error) {
-	return getPoolBalanceWithBaseURL(defaultAPIBaseURL, poolID)
+func getPoolStatusWithBaseURL(ctx context.Context, client *http.Client, baseURL, poolID string) (PoolStatus, error) {
+	info, err := getPoolInfoWithBaseURL(ctx, client, baseURL, poolID)
+	if err != nil {
+		return PoolStatus{}, err
+	}
+	return info.Status(), nil
 }
 
-func getPoolBalanceWithBaseURL(baseURL, poolID string) (int64, error) {
+// getPoolInfoWithBaseURL maps a 404 to PoolStateNotFound and a pool whose
+// staker balance has been withdrawn to PoolStateDecommissioned. New pools
+// always carry the pledge, so a zero staker balance cannot mean "just created".
+func getPoolInfoWithBaseURL(ctx context.Context, client *http.Client, baseURL, poolID string) (PoolInfo, error) {
 	url := fmt.Sprintf("%s/api/v2/pool/%s", baseURL, poolID)
-	resp, err := getWithRetry(url, 3)
+	body, err := getJSON(ctx, client, url)
+	if errors.Is(err, ErrNotFound) {
+		return PoolInfo{PoolID: poolID, State: PoolStateNotFound}, nil
+	}
 	if err != nil {
-		return 0, err
+		return PoolInfo{}, err
 	}
-	defer resp.Body.Close()
+	return parsePoolInfo(url, poolID, body)
+}
 
-	body, err := io.ReadAll(resp.Body)
+// listPageSize is how many items are asked for per request when paging
+// through a list endpoint.
+const listPageSize = 100
+
+// getPagedList pages through a list endpoint with offset and items. parse
+// returns an item's ID and value. A page shorter than asked for, or one with
+// nothing new from a server that ignores paging, ends the listing.
+func getPagedList[T any](ctx context.Context, client *http.Client, listURL string, parse func(url string, item gjson.Result) (string, T, error)) ([]T, error) {
+	var values []T
+	seen := make(map[string]bool)
+	for offset := 0; ; offset += listPageSize {
+		url := fmt.Sprintf("%s?offset=%d&items=%d", listURL, offset, listPageSize)
+		body, err := getJSON(ctx, client, url)
+		if err != nil {
+			return nil, err
+		}
+		page := gjson.ParseBytes(body)
+		if !page.IsArray() {
+			return nil, malformedResponse(url, errors.New("expected a list"))
+		}
+		added := 0
+		for _, item := range page.Array() {
+			id, value, err := parse(url, item)
+			if err != nil {
+				return nil, err
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			values = append(values, value)
+			added++
+		}
+		if len(page.Array()) < listPageSize || added == 0 {
+			return values, nil
+		}
+	}
+}
+
+func getPoolDelegationsWithBaseURL(ctx context.Context, client *http.Client, baseURL, poolID string) ([]DelegationInfo, error) {
+	listURL := fmt.Sprintf("%s/api/v2/pool/%s/delegations", baseURL, poolID)
+	return getPagedList(ctx, client, listURL, func(url string, item gjson.Result) (string, DelegationInfo, error) {
+		info, err := parseListedDelegation(url, item)
+		info.PoolID = poolID
+		return info.DelegationID, info, err
+	})
+}
+
+// getAddressDelegationsWithBaseURL returns the delegations whose spend
+// destination is address. Like the balance, an address the server has never
+// seen simply has none.
+func getAddressDelegationsWithBaseURL(ctx context.Context, client *http.Client, baseURL, address string) ([]DelegationInfo, error) {
+	url := fmt.Sprintf("%s/api/v2/address/%s/delegations", baseURL, address)
+	body, err := getJSON(ctx, client, url)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	list := gjson.ParseBytes(body)
+	if !list.IsArray() {
+		return nil, malformedResponse(url, errors.New("expected a list of delegations"))
+	}
+	var delegations []DelegationInfo
+	for _, item := range list.Array() {
+		info, err := parseListedDelegation(url, item)
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, info)
 	}
+	return delegations, nil
+}
 
-	atoms_balance := gjson.GetBytes(body, "staker_balance.atoms").Int()
-	ml_balance := atoms_balance / PRECISION
-	return ml_balance, nil
+// getPoolListWithBaseURL lists the pools of the network. The API server has
+// no lookup of pools by owner, so finding an address's pools means scanning
+// this list.
+func getPoolListWithBaseURL(ctx context.Context, client *http.Client, baseURL string) ([]PoolInfo, error) {
+	return getPagedList(ctx, client, baseURL+"/api/v2/pool", func(url string, item gjson.Result) (string, PoolInfo, error) {
+		poolID := item.Get("pool_id").String()
+		if poolID == "" {
+			return "", PoolInfo{}, malformedResponse(url, errors.New("pool without pool_id"))
+		}
+		info, err := parsePoolInfo(url, poolID, []byte(item.Raw))
+		return poolID, info, err
+	})
 }
 
-func getDelegationBalance(delegationID string) (int64, error) {
-	return getDelegationBalanceWithBaseURL(defaultAPIBaseURL, delegationID)
+// parseListedDelegation reads a delegation from a list endpoint, which
+// unlike /api/v2/delegation/{id} includes the delegation ID.
+func parseListedDelegation(url string, item gjson.Result) (DelegationInfo, error) {
+	delegationID := item.Get("delegation_id").String()
+	if delegationID == "" {
+		return DelegationInfo{}, malformedResponse(url, errors.New("delegation without delegation_id"))
+	}
+	balance, err := parseAtomsField(url, []byte(item.Raw), "balance.atoms")
+	if err != nil {
+		return DelegationInfo{}, err
+	}
+	return DelegationInfo{
+		DelegationID:        delegationID,
+		PoolID:              item.Get("pool_id").String(),
+		SpendDestination:    item.Get("spend_destination").String(),
+		CreationBlockHeight: item.Get("creation_block_height").Int(),
+		Balance:             balance,
+	}, nil
 }
 
-func getDelegationBalanceWithBaseURL(baseURL, delegationID string) (int64, error) {
+func parsePoolInfo(url, poolID string, body []byte) (PoolInfo, error) {
+	stakerBalance, err := parseAtomsField(url, body, "staker_balance.atoms")
+	if err != nil {
+		return PoolInfo{}, err
+	}
+	info := PoolInfo{
+		PoolID:                  poolID,
+		State:                   PoolStateActive,
+		StakerBalance:           stakerBalance,
+		VRFPublicKey:            gjson.GetBytes(body, "vrf_public_key").String(),
+		StakerDestination:       gjson.GetBytes(body, "staker_destination").String(),
+		DecommissionDestination: gjson.GetBytes(body, "decommission_destination").String(),
+	}
+	if stakerBalance.IsZero() {
+		info.State = PoolStateDecommissioned
+	}
+	if info.DelegationsBalance, err = parseOptionalAtomsField(url, body, "delegations_balance.atoms"); err != nil {
+		return PoolInfo{}, err
+	}
+	if info.CostPerBlock, err = parseOptionalAtomsField(url, body, "cost_per_block.atoms"); err != nil {
+		return PoolInfo{}, err
+	}
+	if info.MarginRatioPerThousand, err = parseMarginRatio(gjson.GetBytes(body, "margin_ratio_per_thousand")); err != nil {
+		return PoolInfo{}, malformedResponse(url, err)
+	}
+	return info, nil
+}
+
+// parseMarginRatio accepts the forms the API server has used for
+// margin_ratio_per_thousand: a per-thousand integer, a percentage such as
+// "2.5%" or a fraction such as "0.025".
+func parseMarginRatio(field gjson.Result) (int64, error) {
+	if !field.Exists() {
+		return 0, nil
+	}
+	if field.Type == gjson.Number {
+		return field.Int(), nil
+	}
+	raw := strings.TrimSpace(field.String())
+	scale := 1000.0
+	if strings.HasSuffix(raw, "%") {
+		raw = strings.TrimSuffix(raw, "%")
+		scale = 10
+	} else if !strings.Contains(raw, ".") {
+		scale = 1
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid margin ratio %q", field.String())
+	}
+	return int64(math.Round(value * scale)), nil
+}
+
+func getDelegationBalanceWithBaseURL(ctx context.Context, client *http.Client, baseURL, delegationID string) (Amount, error) {
+	info, err := getDelegationInfoWithBaseURL(ctx, client, baseURL, delegationID)
+	if err != nil {
+		return Amount{}, err
+	}
+	return info.Balance, nil
+}
+
+func getDelegationInfoWithBaseURL(ctx context.Context, client *http.Client, baseURL, delegationID string) (DelegationInfo, error) {
 	url := fmt.Sprintf("%s/api/v2/delegation/%s", baseURL, delegationID)
-	resp, err := getWithRetry(url, 3)
+	body, err := getJSON(ctx, client, url)
 	if err != nil {
-		return 0, err
+		return DelegationInfo{}, err
+	}
+	balance, err := parseAtomsField(url, body, "balance.atoms")
+	if err != nil {
+		return DelegationInfo{}, err
+	}
+	return DelegationInfo{
+		DelegationID:        delegationID,
+		PoolID:              gjson.GetBytes(body, "pool_id").String(),
+		SpendDestination:    gjson.GetBytes(body, "spend_destination").String(),
+		CreationBlockHeight: gjson.GetBytes(body, "creation_block_height").Int(),
+		Balance:             balance,
+	}, nil
+}
+
+// getAddressBalanceWithBaseURL returns the spendable coins and fungible
+// tokens of an address. The API server answers 404 for addresses that never
+// appeared on chain, which for a valid address simply means an empty balance.
+func getAddressBalanceWithBaseURL(ctx context.Context, client *http.Client, baseURL, address string) (AddressBalance, error) {
+	url := fmt.Sprintf("%s/api/v2/address/%s", baseURL, address)
+	body, err := getJSON(ctx, client, url)
+	if errors.Is(err, ErrNotFound) {
+		return AddressBalance{Tokens: map[string]Amount{}}, nil
+	}
+	if err != nil {
+		return AddressBalance{}, err
+	}
+	coins, err := parseAtomsField(url, body, "coin_balance.atoms")
+	if err != nil {
+		return AddressBalance{}, err
+	}
+	balance := AddressBalance{Coins: coins, Tokens: map[string]Amount{}}
+	for _, token := range gjson.GetBytes(body, "tokens").Array() {
+		tokenID := token.Get("token_id").String()
+		if tokenID == "" {
+			return AddressBalance{}, malformedResponse(url, errors.New("token without token_id"))
+		}
+		amount, err := ParseAmountAtoms(token.Get("amount.atoms").String())
+		if err != nil {
+			return AddressBalance{}, malformedResponse(url, err)
+		}
+		balance.Tokens[tokenID] = balance.Tokens[tokenID].Add(amount)
+	}
+	for _, txID := range gjson.GetBytes(body, "transaction_history").Array() {
+		balance.TransactionIDs = append(balance.TransactionIDs, txID.String())
+	}
+	return balance, nil
+}
+
+// getAddressHistoryWithBaseURL returns count transactions of address from
+// offset into its history, newest first. Each transaction is a separate
+// request, so pages are kept small.
+func getAddressHistoryWithBaseURL(ctx context.Context, client *http.Client, baseURL, address string, offset, count int) (AddressHistory, error) {
+	balance, err := getAddressBalanceWithBaseURL(ctx, client, baseURL, address)
+	if err != nil {
+		return AddressHistory{}, err
+	}
+	txIDs := balance.TransactionIDs
+	start := min(max(offset, 0), len(txIDs))
+	txs, err := getTransactionsWithBaseURL(ctx, client, baseURL, txIDs[start:min(start+count, len(txIDs))])
+	if err != nil {
+		return AddressHistory{}, err
+	}
+	return AddressHistory{Total: len(txIDs), Transactions: txs}, nil
+}
+
+func getTransactionsWithBaseURL(ctx context.Context, client *http.Client, baseURL string, txIDs []string) ([]TransactionInfo, error) {
+	txs := make([]TransactionInfo, 0, len(txIDs))
+	for _, txID := range txIDs {
+		tx, err := getTransactionWithBaseURL(ctx, client, baseURL, txID)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+func getTransactionWithBaseURL(ctx context.Context, client *http.Client, baseURL, txID string) (TransactionInfo, error) {
+	url := fmt.Sprintf("%s/api/v2/transaction/%s", baseURL, txID)
+	body, err := getJSON(ctx, client, url)
+	if err != nil {
+		return TransactionInfo{}, err
+	}
+	return parseTransaction(url, txID, body)
+}
+
+func parseTransaction(url, txID string, body []byte) (TransactionInfo, error) {
+	tx := TransactionInfo{TxID: txID}
+	if gjson.GetBytes(body, "block_id").String() != "" {
+		height := gjson.GetBytes(body, "block_height")
+		if !height.Exists() {
+			return TransactionInfo{}, malformedResponse(url, errors.New("missing block_height"))
+		}
+		tx.BlockHeight = height.Int()
+		// The timestamp is a number or a numeric string depending on the
+		// server version.
+		tx.Timestamp = time.Unix(gjson.GetBytes(body, "timestamp").Int(), 0).UTC()
+	}
+	for _, input := range gjson.GetBytes(body, "inputs").Array() {
+		transfer, ok, err := parseTxTransfer(url, input.Get("utxo"))
+		if err != nil {
+			return TransactionInfo{}, err
+		}
+		if ok {
+			tx.Inputs = append(tx.Inputs, transfer)
+		}
+	}
+	for _, output := range gjson.GetBytes(body, "outputs").Array() {
+		transfer, ok, err := parseTxTransfer(url, output)
+		if err != nil {
+			return TransactionInfo{}, err
+		}
+		if ok {
+			tx.Outputs = append(tx.Outputs, transfer)
+		}
+	}
+	return tx, nil
+}
+
+// parseTxTransfer reads the ML coins of a transaction output, or of the
+// output an input spends. Token outputs and outputs without coins, such as
+// delegation creation, are skipped.
+func parseTxTransfer(url string, output gjson.Result) (TxTransfer, bool, error) {
+	var atoms gjson.Result
+	destination := output.Get("destination").String()
+	switch {
+	case output.Get("value.type").String() == "Coin":
+		atoms = output.Get("value.amount.atoms")
+	case output.Get("type").String() == "DelegateStaking":
+		atoms = output.Get("amount.atoms")
+		destination = output.Get("delegation_id").String()
+	default:
+		return TxTransfer{}, false, nil
+	}
+	coins, err := ParseAmountAtoms(atoms.String())
+	if err != nil {
+		return TxTransfer{}, false, malformedResponse(url, err)
+	}
+	return TxTransfer{Destination: destination, Coins: coins}, true, nil
+}
+
+func getTokenInfoWithBaseURL(ctx context.Context, client *http.Client, baseURL, tokenID string) (TokenInfo, error) {
+	url := fmt.Sprintf("%s/api/v2/token/%s", baseURL, tokenID)
+	body, err := getJSON(ctx, client, url)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	decimals := gjson.GetBytes(body, "number_of_decimals")
+	if !decimals.Exists() {
+		return TokenInfo{}, malformedResponse(url, errors.New("missing number_of_decimals"))
+	}
+	ticker := gjson.GetBytes(body, "token_ticker.string")
+	if !ticker.Exists() {
+		ticker = gjson.GetBytes(body, "token_ticker")
+	}
+	return TokenInfo{
+		TokenID:  tokenID,
+		Ticker:   ticker.String(),
+		Decimals: int(decimals.Int()),
+	}, nil
+}
+
+func parseOptionalAtomsField(url string, body []byte, path string) (Amount, error) {
+	if !gjson.GetBytes(body, path).Exists() {
+		return Amount{}, nil
+	}
+	return parseAtomsField(url, body, path)
+}
+
+func parseAtomsField(url string, body []byte, path string) (Amount, error) {
+	field := gjson.GetBytes(body, path)
+	if !field.Exists() {
+		return Amount{}, malformedResponse(url, fmt.Errorf("missing %s", path))
+	}
+	amount, err := ParseAmountAtoms(field.String())
+	if err != nil {
+		return Amount{}, malformedResponse(url, err)
+	}
+	return amount, nil
+}
+
+// getJSON fetches url and returns the body of a successful JSON response.
+// Non-2xx statuses and bodies that are not JSON are reported as *APIError.
+func getJSON(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	resp, err := getWithRetry(ctx, client, url, 3)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, newStatusError(url, resp)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	if !gjson.ValidBytes(body) {
+		return nil, malformedResponse(url, errors.New("invalid JSON body"))
 	}
-	atoms_balance := gjson.GetBytes(body, "balance.atoms").Int()
-	ml_balance := atoms_balance / PRECISION
-	return ml_balance, nil
+	return body, nil
 }
 
-func getWithRetry(url string, attempts int) (*http.Response, error) {
+var (
+	// retryBaseDelay is the wait before the first retry; it doubles with
+	// every attempt up to retryMaxDelay.
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+	// retryBudget bounds the time one request may spend on attempts and
+	// waits, or less when the caller's context has an earlier deadline.
+	retryBudget = 15 * time.Second
+)
+
+// getWithRetry sends a GET request, retrying transport errors, 429 and 5xx
+// responses with exponential backoff and jitter. A Retry-After header
+// replaces a shorter backoff. Retryable statuses are returned as *APIError
+// once the attempts run out; when the next wait does not fit in the budget
+// the error also wraps ErrRetryBudgetExceeded.
+func getWithRetry(ctx context.Context, client *http.Client, url string, attempts int) (*http.Response, error) {
+	deadline := time.Now().Add(retryBudget)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
 	var lastErr error
 	for i := 0; i < attempts; i++ {
-		resp, err := httpClient.Get(url)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		wait := retryDelay(i)
+		resp, err := client.Do(req)
 		if err == nil {
-			return resp, nil
+			if !isRetryableStatus(resp.StatusCode) {
+				return resp, nil
+			}
+			apiErr := newStatusError(url, resp)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			lastErr = apiErr
+			wait = max(wait, apiErr.RetryAfter)
+		} else {
+			lastErr = err
+			if ctx.Err() != nil || !isRetryableHTTPError(err) {
+				break
+			}
 		}
-		lastErr = err
-		if !isRetryableHTTPError(err) {
+		if i == attempts-1 {
 			break
 		}
-		time.Sleep(time.Duration(i+1) * 500 * time.Millisecond)
+		if time.Now().Add(wait).After(deadline) {
+			return nil, retryBudgetExceeded(lastErr)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
 	}
 	return nil, lastErr
 }
 
+// retryDelay is the backoff after attempt i, with the upper half jittered so
+// clients that failed together do not retry together.
+func retryDelay(i int) time.Duration {
+	delay := retryMaxDelay
+	if i < 16 && retryBaseDelay<<i < retryMaxDelay {
+		delay = retryBaseDelay << i
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half))
+	}
+	return delay
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || (code >= 500 && code != http.StatusNotImplemented)
+}
+
+func retryBudgetExceeded(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Err = ErrRetryBudgetExceeded
+		return apiErr
+	}
+	return fmt.Errorf("%w: %w", ErrRetryBudgetExceeded, err)
+}
+
 func isRetryableHTTPError(err error) bool {
 	if errors.Is(err, io.EOF) {
 		return true
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestGetPoolStatusErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		kind    error
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}, ErrServer},
+		{"rate limited", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, ErrRateLimited},
+		{"html body", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("<html>bad gateway</html>"))
+		}, ErrMalformedResponse},
+		{"missing field", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"margin_ratio_per_thousand":"10"}`))
+		}, ErrMalformedResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client := NewHTTPBalanceClient(server.URL)
+			_, err := client.GetPoolStatus(context.Background(), "mpool1test")
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("expected %v, got %v", tt.kind, err)
+			}
+		})
+	}
+}
+
+func TestGetPoolStatusLifecycle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/pool/mpool1active":
+			_, _ = w.Write([]byte(`{"staker_balance":{"atoms":"4000000000000000"}}`))
+		case "/api/v2/pool/mpool1gone":
+			_, _ = w.Write([]byte(`{"staker_balance":{"atoms":"0"}}`))
+		default:
+			http.Error(w, `{"error":"Stake pool not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPBalanceClient(server.URL)
+	tests := map[string]PoolStatus{
+		"mpool1active":  {State: PoolStateActive, Balance: AmountFromML(40000)},
+		"mpool1gone":    {State: PoolStateDecommissioned},
+		"mpool1missing": {State: PoolStateNotFound},
+	}
+	for poolID, expected := range tests {
+		status, err := client.GetPoolStatus(context.Background(), poolID)
+		if err != nil {
+			t.Fatalf("%s: GetPoolStatus failed: %v", poolID, err)
+		}
+		if status.State != expected.State || status.Balance.Cmp(expected.Balance) != 0 {
+			t.Fatalf("%s: expected %v/%s, got %v/%s", poolID, expected.State, expected.Balance, status.State, status.Balance)
+		}
+	}
+}
+
+func TestGetPoolStatusRateLimitRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPBalanceClient(server.URL).GetPoolStatus(context.Background(), "mpool1test")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T", err)
+	}
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Fatalf("expected Retry-After 7s, got %v", apiErr.RetryAfter)
+	}
+}
+
+func TestGetWithRetryRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"blocks":12}`))
+	}))
+	defer server.Close()
+
+	height, err := getBlocksWithBaseURL(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if height != 12 || calls.Load() != 3 {
+		t.Fatalf("expected height 12 after 3 calls, got %d after %d", height, calls.Load())
+	}
+}
+
+func TestGetWithRetryDoesNotRetryNotFound(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	_, err := getBlocksWithBaseURL(context.Background(), server.Client(), server.URL)
+	if !errors.Is(err, ErrNotFound) || calls.Load() != 1 {
+		t.Fatalf("expected one not found call, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestGetWithRetryHonoursRetryAfter(t *testing.T) {
+	oldBudget := retryBudget
+	retryBudget = 5 * time.Second
+	defer func() { retryBudget = oldBudget }()
+
+	var calls atomic.Int32
+	var first time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if waited := time.Since(first); waited < time.Second {
+			t.Errorf("retried after %v, before Retry-After", waited)
+		}
+		_, _ = w.Write([]byte(`{"blocks":12}`))
+	}))
+	defer server.Close()
+
+	if _, err := getBlocksWithBaseURL(context.Background(), server.Client(), server.URL); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+}
+
+func TestGetWithRetryStopsWhenBudgetRunsOut(t *testing.T) {
+	oldBudget := retryBudget
+	retryBudget = time.Minute
+	defer func() { retryBudget = oldBudget }()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// The caller's deadline is shorter than the budget and the Retry-After.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := getBlocksWithBaseURL(ctx, server.Client(), server.URL)
+	if !errors.Is(err, ErrServer) || !errors.Is(err, ErrRetryBudgetExceeded) {
+		t.Fatalf("expected a server error over budget, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 5*time.Second {
+		t.Fatalf("expected the Retry-After to be kept, got %v", err)
+	}
+	if calls.Load() != 1 || time.Since(start) > time.Second {
+		t.Fatalf("expected to give up at once, got %d calls in %v", calls.Load(), time.Since(start))
+	}
+}
+
+func TestGetDelegationBalanceParsesAtoms(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/delegation/mdelg1test" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"balance":{"atoms":"123450000000","decimal":"1.2345"}}`))
+	}))
+	defer server.Close()
+
+	balance, err := NewHTTPBalanceClient(server.URL).GetDelegationBalance(context.Background(), "mdelg1test")
+	if err != nil {
+		t.Fatalf("GetDelegationBalance failed: %v", err)
+	}
+	if balance.Cmp(AmountFromAtoms(123450000000)) != 0 {
+		t.Fatalf("unexpected balance %s", balance)
+	}
+}
+
+func TestGetPoolStatusHonoursContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewHTTPBalanceClient(server.URL).GetPoolStatus(ctx, "mpool1test")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestGetPoolInfoParsesFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/blocks" {
+			_, _ = w.Write([]byte(`{"blocks": 1234}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"staker_balance": {"atoms": "4000000000000000", "decimal": "40000"},
+			"delegations_balance": {"atoms": "150000000000000", "decimal": "1500"},
+			"margin_ratio_per_thousand": "2.5%",
+			"cost_per_block": {"atoms": "5000000000000", "decimal": "50"},
+			"vrf_public_key": "vrfpub1abc",
+			"decommission_destination": "mtc1decommission"
+		}`))
+	}))
+	defer server.Close()
+
+	info, err := NewHTTPBalanceClient(server.URL).GetPoolInfo(context.Background(), "mpool1test")
+	if err != nil {
+		t.Fatalf("GetPoolInfo failed: %v", err)
+	}
+	if info.State != PoolStateActive {
+		t.Fatalf("expected active pool, got %v", info.State)
+	}
+	if info.TotalStake().Cmp(AmountFromML(41500)) != 0 {
+		t.Fatalf("unexpected total stake %s", info.TotalStake())
+	}
+	if info.MarginRatioPerThousand != 25 {
+		t.Fatalf("unexpected margin ratio %d", info.MarginRatioPerThousand)
+	}
+	if info.CostPerBlock.Cmp(AmountFromML(50)) != 0 {
+		t.Fatalf("unexpected cost per block %s", info.CostPerBlock)
+	}
+	if info.VRFPublicKey != "vrfpub1abc" || info.DecommissionDestination != "mtc1decommission" {
+		t.Fatalf("unexpected keys: %+v", info)
+	}
+	if info.Height != 1234 {
+		t.Fatalf("expected the server's tip height, got %d", info.Height)
+	}
+}
+
+func TestGetAddressPoolsReusesPoolList(t *testing.T) {
+	// The pool list as /api/v2/pool encodes it.
+	list, err := os.ReadFile("testdata/pool_list.json")
+	if err != nil {
+		t.Fatalf("reading the fixture failed: %v", err)
+	}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(list)
+	}))
+	defer server.Close()
+
+	client := NewHTTPBalanceClient(server.URL)
+	for i := 0; i < 2; i++ {
+		pools, err := client.GetAddressPools(context.Background(), "mtc1qx5g8ulhmhv2tttjmfd4ltuw0dkyeqalgz7qmmaq")
+		if err != nil {
+			t.Fatalf("GetAddressPools failed: %v", err)
+		}
+		if len(pools) != 1 || pools[0].PoolID != testMainnetPoolID || pools[0].TotalStake().Cmp(AmountFromML(41500)) != 0 {
+			t.Fatalf("unexpected pools %+v", pools)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected the pool list to be fetched once, got %d requests", got)
+	}
+	pools, err := client.GetAddressPools(context.Background(), "mtc1qxe4ycv3m0yd5sr9ljmxvzcxs3e4jc2azadmvg2p")
+	if err != nil || len(pools) != 1 || pools[0].PoolID != "mpool1pc83qygjzv2p29shrqv35xcur50p7gppxj4s2t" {
+		t.Fatalf("expected the pool staked to the address, got %+v, %v", pools, err)
+	}
+}
+
+func TestParseMarginRatio(t *testing.T) {
+	body := []byte(`{"number": 25, "percent": "2.5%", "fraction": "0.025", "integer": "25"}`)
+	for _, path := range []string{"number", "percent", "fraction", "integer"} {
+		got, err := parseMarginRatio(gjson.GetBytes(body, path))
+		if err != nil {
+			t.Fatalf("%s: parseMarginRatio failed: %v", path, err)
+		}
+		if got != 25 {
+			t.Fatalf("%s: expected 25, got %d", path, got)
+		}
+	}
+	if _, err := parseMarginRatio(gjson.Parse(`"abc"`)); err == nil {
+		t.Fatal("expected error for invalid margin ratio")
+	}
+}
+
+func TestGetDelegationInfoParsesFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/blocks" {
+			_, _ = w.Write([]byte(`{"blocks": 1234}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"balance": {"atoms": "25000000000000", "decimal": "250"},
+			"creation_block_height": 12345,
+			"delegation_id": "mdelg1test",
+			"next_nonce": 3,
+			"pool_id": "mpool1test",
+			"spend_destination": "mtc1owner"
+		}`))
+	}))
+	defer server.Close()
+
+	info, err := NewHTTPBalanceClient(server.URL).GetDelegationInfo(context.Background(), "mdelg1test")
+	if err != nil {
+		t.Fatalf("GetDelegationInfo failed: %v", err)
+	}
+	expected := DelegationInfo{
+		DelegationID:        "mdelg1test",
+		PoolID:              "mpool1test",
+		SpendDestination:    "mtc1owner",
+		CreationBlockHeight: 12345,
+		Height:              1234,
+	}
+	if info.PoolID != expected.PoolID || info.SpendDestination != expected.SpendDestination ||
+		info.CreationBlockHeight != expected.CreationBlockHeight || info.DelegationID != expected.DelegationID ||
+		info.Height != expected.Height {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if info.Balance.Cmp(AmountFromML(250)) != 0 {
+		t.Fatalf("unexpected balance %s", info.Balance)
+	}
+}
+
+func TestGetAddressBalanceParsesTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/address/mtc1funded":
+			_, _ = w.Write([]byte(`{
+				"coin_balance": {"atoms": "150000000000", "decimal": "1.5"},
+				"tokens": [
+					{"token_id": "mmltk1a", "amount": {"atoms": "1000", "decimal": "10"}},
+					{"token_id": "mmltk1b", "amount": {"atoms": "7", "decimal": "7"}}
+				]
+			}`))
+		case "/api/v2/token/mmltk1a":
+			_, _ = w.Write([]byte(`{"number_of_decimals": 2, "token_ticker": {"string": "USDX", "hex": "55534458"}}`))
+		default:
+			http.Error(w, `{"error":"Address not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPBalanceClient(server.URL)
+	balance, err := client.GetAddressBalance(context.Background(), "mtc1funded")
+	if err != nil {
+		t.Fatalf("GetAddressBalance failed: %v", err)
+	}
+	if balance.Coins.Cmp(AmountFromAtoms(150000000000)) != 0 {
+		t.Fatalf("unexpected coins %s", balance.Coins)
+	}
+	if len(balance.Tokens) != 2 || balance.Tokens["mmltk1a"].Cmp(AmountFromAtoms(1000)) != 0 {
+		t.Fatalf("unexpected tokens %v", balance.Tokens)
+	}
+
+	empty, err := client.GetAddressBalance(context.Background(), "mtc1unused")
+	if err != nil || !empty.Coins.IsZero() || len(empty.Tokens) != 0 {
+		t.Fatalf("expected empty balance for unknown address, got %+v, %v", empty, err)
+	}
+
+	info, err := client.GetTokenInfo(context.Background(), "mmltk1a")
+	if err != nil {
+		t.Fatalf("GetTokenInfo failed: %v", err)
+	}
+	if info.Ticker != "USDX" || info.Decimals != 2 {
+		t.Fatalf("unexpected token info %+v", info)
+	}
+	if got := info.Format(balance.Tokens["mmltk1a"]); got != "10 USDX" {
+		t.Fatalf("unexpected formatted amount %q", got)
+	}
+}
+
+func TestGetBlockParsesPoolAndReward(t *testing.T) {
+	// A proof-of-stake block as /api/v2/block encodes it: the reward output
+	// names the pool and carries no amount.
+	posBlock, err := os.ReadFile("testdata/block_pos.json")
+	if err != nil {
+		t.Fatalf("reading the fixture failed: %v", err)
+	}
+	tests := []struct {
+		name   string
+		block  string
+		poolID string
+		reward Amount
+	}{
+		{"proof of stake", string(posBlock), testMainnetPoolID, Amount{}},
+		{"pool from reward output", `{
+			"header": {"timestamp": {"timestamp": 1760704200}, "consensus_data": {"PoS": {}}},
+			"body": {"reward": [{"type": "ProduceBlockFromStake", "pool_id": "mpool1test"}]}
+		}`, "mpool1test", Amount{}},
+		{"coin reward", `{
+			"header": {
+				"timestamp": {"timestamp": 1760704200},
+				"consensus_data": {"PoS": {"stake_pool_id": "mpool1test"}}
+			},
+			"body": {"reward": [
+				{"type": "ProduceBlockFromStake", "pool_id": "mpool1test"},
+				{"type": "Transfer", "value": {"amount": {"atoms": "20200000000000"}}}
+			]}
+		}`, "mpool1test", AmountFromML(202)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v2/chain/1234":
+					_, _ = w.Write([]byte(`"b1234"`))
+				case "/api/v2/block/b1234":
+					_, _ = w.Write([]byte(tt.block))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			block, err := NewHTTPBalanceClient(server.URL).GetBlock(context.Background(), 1234)
+			if err != nil {
+				t.Fatalf("GetBlock failed: %v", err)
+			}
+			if block.Height != 1234 || block.ID != "b1234" || block.PoolID != tt.poolID {
+				t.Fatalf("unexpected block %+v", block)
+			}
+			if !block.Timestamp.Equal(time.Unix(1760704200, 0)) {
+				t.Fatalf("unexpected timestamp %v", block.Timestamp)
+			}
+			if block.Reward.Cmp(tt.reward) != 0 {
+				t.Fatalf("expected reward %s, got %s", tt.reward, block.Reward)
+			}
+		})
+	}
+}
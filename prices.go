@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	defaultPriceURL      = "https://api.coingecko.com/api/v3/simple/price?ids=mintlayer&vs_currencies={currency}"
+	defaultPriceJSONPath = "mintlayer.{currency}"
+	defaultPriceCacheTTL = 5 * time.Minute
+)
+
+// ErrUnknownCurrency is returned by a PriceProvider that has no price in the
+// requested currency.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// PriceProvider gives the price of one ML in a fiat currency. Currencies are
+// upper-case ISO 4217 codes such as "USD".
+type PriceProvider interface {
+	Price(ctx context.Context, currency string) (float64, error)
+}
+
+// StaticPriceProvider serves fixed prices by currency, for offline use and
+// tests.
+type StaticPriceProvider map[string]float64
+
+func (p StaticPriceProvider) Price(ctx context.Context, currency string) (float64, error) {
+	price, ok := p[currency]
+	if !ok {
+		return 0, ErrUnknownCurrency
+	}
+	return price, nil
+}
+
+// FilePriceProvider reads prices from a JSON object of currency codes and
+// ML prices, e.g. {"USD": 0.05}. The file is read on every lookup so it can
+// be updated while the bot runs.
+type FilePriceProvider struct {
+	path string
+}
+
+func NewFilePriceProvider(path string) *FilePriceProvider {
+	return &FilePriceProvider{path: path}
+}
+
+func (p *FilePriceProvider) Price(ctx context.Context, currency string) (float64, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return 0, err
+	}
+	var prices map[string]float64
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return 0, fmt.Errorf("price file %s: %w", p.path, err)
+	}
+	return StaticPriceProvider(prices).Price(ctx, currency)
+}
+
+// HTTPPriceProvider fetches prices from a JSON endpoint. In the URL and the
+// gjson path "{currency}" is replaced by the lower-case currency code.
+// Prices are cached for ttl; failed lookups are not cached.
+type HTTPPriceProvider struct {
+	url      string
+	jsonPath string
+	ttl      time.Duration
+	client   *http.Client
+	now      func() time.Time
+
+	mu     sync.Mutex
+	prices map[string]cachedPrice
+}
+
+type cachedPrice struct {
+	price     float64
+	fetchedAt time.Time
+}
+
+// priceHTTPClient is kept apart from httpClient so price lookups do not count
+// against the Mintlayer API rate limit.
+var priceHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// NewHTTPPriceProvider returns a provider for the given endpoint, or for
+// CoinGecko when urlTemplate is empty. A ttl of 0 keeps the default.
+func NewHTTPPriceProvider(urlTemplate, jsonPath string, ttl time.Duration) *HTTPPriceProvider {
+	if urlTemplate == "" {
+		urlTemplate, jsonPath = defaultPriceURL, defaultPriceJSONPath
+	}
+	if ttl == 0 {
+		ttl = defaultPriceCacheTTL
+	}
+	return &HTTPPriceProvider{
+		url:      urlTemplate,
+		jsonPath: jsonPath,
+		ttl:      ttl,
+		client:   priceHTTPClient,
+		now:      time.Now,
+		prices:   make(map[string]cachedPrice),
+	}
+}
+
+func (p *HTTPPriceProvider) Price(ctx context.Context, currency string) (float64, error) {
+	p.mu.Lock()
+	cached, ok := p.prices[currency]
+	p.mu.Unlock()
+	if ok && p.now().Sub(cached.fetchedAt) < p.ttl {
+		return cached.price, nil
+	}
+
+	code := strings.ToLower(currency)
+	endpoint := strings.ReplaceAll(p.url, "{currency}", code)
+	body, err := getJSON(ctx, p.client, endpoint)
+	if err != nil {
+		return 0, err
+	}
+	price := gjson.GetBytes(body, strings.ReplaceAll(p.jsonPath, "{currency}", code))
+	if !price.Exists() {
+		return 0, ErrUnknownCurrency
+	}
+	if price.Type != gjson.Number || price.Float() <= 0 {
+		return 0, malformedResponse(endpoint, fmt.Errorf("invalid price %q", price.Raw))
+	}
+
+	p.mu.Lock()
+	p.prices[currency] = cachedPrice{price: price.Float(), fetchedAt: p.now()}
+	p.mu.Unlock()
+	return price.Float(), nil
+}
+
+// PriceProviderConfig selects where fiat prices come from: "http" (the
+// default) fetches URL, "file" reads File and "static" uses Prices.
+type PriceProviderConfig struct {
+	Type         string             `json:"type,omitempty"`
+	URL          string             `json:"url,omitempty"`
+	JSONPath     string             `json:"json_path,omitempty"`
+	CacheSeconds int                `json:"cache_seconds,omitempty"`
+	File         string             `json:"file,omitempty"`
+	Prices       map[string]float64 `json:"prices,omitempty"`
+}
+
+func buildPriceProvider(config PriceProviderConfig) (PriceProvider, error) {
+	switch config.Type {
+	case "", "http":
+		if config.URL != "" && config.JSONPath == "" {
+			return nil, errors.New("price_provider: json_path is required with url")
+		}
+		return NewHTTPPriceProvider(config.URL, config.JSONPath, time.Duration(config.CacheSeconds)*time.Second), nil
+	case "file":
+		if config.File == "" {
+			return nil, errors.New("price_provider: file is required")
+		}
+		return NewFilePriceProvider(config.File), nil
+	case "static":
+		prices := make(StaticPriceProvider, len(config.Prices))
+		for currency, price := range config.Prices {
+			prices[strings.ToUpper(currency)] = price
+		}
+		return prices, nil
+	default:
+		return nil, fmt.Errorf("price_provider: unknown type %q", config.Type)
+	}
+}
+
+// parseCurrency validates a currency code given by a user and returns it in
+// upper case.
+func parseCurrency(input string) (string, bool) {
+	if len(input) != 3 {
+		return "", false
+	}
+	for _, r := range input {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return "", false
+		}
+	}
+	return strings.ToUpper(input), true
+}
+
+// fiatRate converts ML amounts to a user's currency. The zero value converts
+// nothing.
+type fiatRate struct {
+	currency string
+	price    float64
+}
+
+// approx renders the value of amount, e.g. "≈ 1,234.56 USD", or "" without
+// a rate.
+func (r fiatRate) approx(amount Amount) string {
+	if r.currency == "" {
+		return ""
+	}
+	p := message.NewPrinter(language.AmericanEnglish)
+	return p.Sprintf("≈ %.2f %s", amount.MLFloat()*r.price, r.currency)
+}
+
+// suffix is approx in parentheses after an ML amount, or "" without a rate.
+func (r fiatRate) suffix(amount Amount) string {
+	if r.currency == "" {
+		return ""
+	}
+	return " (" + r.approx(amount) + ")"
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func TestHTTPPriceProviderCachesPrices(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("vs") == "usd" {
+			_, _ = w.Write([]byte(`{"mintlayer": {"usd": 0.05}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"mintlayer": {}}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	provider := NewHTTPPriceProvider(server.URL+"/price?vs={currency}", "mintlayer.{currency}", time.Minute)
+	provider.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		price, err := provider.Price(ctx, "USD")
+		if err != nil || price != 0.05 {
+			t.Fatalf("expected 0.05, got %v, %v", price, err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected the price to be cached, got %d requests", got)
+	}
+	now = now.Add(time.Minute)
+	if _, err := provider.Price(ctx, "USD"); err != nil || requests.Load() != 2 {
+		t.Fatalf("expected the cached price to expire, got %d requests, %v", requests.Load(), err)
+	}
+	if _, err := provider.Price(ctx, "XYZ"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Fatalf("expected ErrUnknownCurrency, got %v", err)
+	}
+}
+
+func TestFilePriceProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	if err := os.WriteFile(path, []byte(`{"EUR": 0.04}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := buildPriceProvider(PriceProviderConfig{Type: "file", File: path})
+	if err != nil {
+		t.Fatalf("buildPriceProvider failed: %v", err)
+	}
+	if price, err := provider.Price(context.Background(), "EUR"); err != nil || price != 0.04 {
+		t.Fatalf("expected 0.04, got %v, %v", price, err)
+	}
+	if _, err := provider.Price(context.Background(), "USD"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Fatalf("expected ErrUnknownCurrency, got %v", err)
+	}
+}
+
+func TestCurrencyHandlerAddsFiatValues(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	ctx := context.Background()
+	exec("INSERT INTO delegations (userID, delegationID, balance_atoms) VALUES ('7', 'd1', '0')")
+	client := &fakeBalanceClient{
+		delegationBalances: map[string]Amount{"d1": AmountFromML(1000)},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", ctx)
+	app.prices = StaticPriceProvider{"USD": 0.05}
+	var messages []string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	command := func(text string) string {
+		t.Helper()
+		messages = nil
+		update := &models.Update{
+			Message: &models.Message{
+				Text: text,
+				Chat: models.Chat{ID: 5},
+				From: &models.User{ID: 7},
+			},
+		}
+		switch text {
+		case "/balance":
+			app.balanceHandler(ctx, nil, update)
+		case "/delegation_list":
+			app.listDelegationsHandler(ctx, nil, update)
+		default:
+			app.currencyHandler(ctx, nil, update)
+		}
+		if len(messages) != 1 {
+			t.Fatalf("expected one reply to %s, got %q", text, messages)
+		}
+		return messages[0]
+	}
+
+	if got := command("/currency eur"); got != "No ML price is available in `EUR`" {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	if got := command("/currency usd"); got != "ML amounts are now also shown in `USD`, 1 ML ≈ 0.05 USD" {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	if got := command("/balance"); got != "`0` pools: `0 ML` (≈ 0.00 USD)\n`1` delegations: `1,000 ML` (≈ 50.00 USD)\nTotal: `1,000 ML` (≈ 50.00 USD)" {
+		t.Fatalf("unexpected balance %q", got)
+	}
+	if got := command("/delegation_list"); got != "Your delegations:\n`d1`: 1,000 ML (≈ 50.00 USD) \n" {
+		t.Fatalf("unexpected delegation list %q", got)
+	}
+
+	messages = nil
+	app.notifyDelegationsBalanceChanges(ctx, "7", 5, 0)
+	if len(messages) != 1 || messages[0] != "`d1`: \\+1,000 ML (≈ 50.00 USD)" {
+		t.Fatalf("unexpected notifications %q", messages)
+	}
+
+	command("/currency off")
+	if got := command("/delegation_list"); got != "Your delegations:\n`d1`: 1,000 ML \n" {
+		t.Fatalf("expected no fiat value after /currency off, got %q", got)
+	}
+}
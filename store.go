@@ -3,20 +3,58 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 )
 
 type Store interface {
-	AddMonitoredAddress(ctx context.Context, userID, address string, threshold int, notifyOnChange bool, chatID int64) error
-	AddPool(ctx context.Context, userID, poolID string) error
+	AddMonitoredAddress(ctx context.Context, userID, address, network string, threshold int, notifyOnChange bool, chatID int64) error
+	RemoveMonitoredAddress(ctx context.Context, userID, address string) error
+	GetMonitoredAddresses(ctx context.Context, userID string) ([]MonitoredAddress, error)
+	UpdateAddressBalance(ctx context.Context, userID, address string, balance Amount) error
+	UpdateAddressLastTx(ctx context.Context, userID, address, txID string) error
+	SetAddressTransferAlerts(ctx context.Context, userID, address string, enabled bool, knownTxIDs []string) error
+	GetTransferAlertAddresses(ctx context.Context, userID string) ([]string, error)
+	GetAddressTransfers(ctx context.Context, userID, address string) (map[string]bool, error)
+	MarkAddressTransfer(ctx context.Context, userID, address, txID string, confirmed bool) error
+	GetAddressTokenBalances(ctx context.Context, userID, address string) (map[string]Amount, error)
+	UpdateAddressTokenBalance(ctx context.Context, userID, address, tokenID string, balance Amount) error
+	AddPool(ctx context.Context, userID, poolID, network string) error
 	RemovePool(ctx context.Context, userID, poolID string) error
 	GetPools(ctx context.Context, userID string) ([]string, error)
-	AddDelegation(ctx context.Context, userID, delegationID string) error
+	GetTrackedPools(ctx context.Context) ([]string, error)
+	GetTrackedNetwork(ctx context.Context, id string) (string, error)
+	AddDelegation(ctx context.Context, userID, delegationID, network string) error
+	ImportPoolsAndDelegations(ctx context.Context, userID, network string, poolIDs, delegationIDs []string) error
+	SetAddressSync(ctx context.Context, userID, address, network string, enabled bool) error
+	GetSyncedAddresses(ctx context.Context, userID string) ([]string, error)
 	RemoveDelegation(ctx context.Context, userID, delegationID string) error
 	GetDelegations(ctx context.Context, userID string) ([]string, error)
-	GetPoolBalance(ctx context.Context, userID, poolID string) (int64, error)
-	UpdatePoolBalance(ctx context.Context, userID, poolID string, balance int64) error
-	GetDelegationBalance(ctx context.Context, userID, delegationID string) (int64, error)
-	UpdateDelegationBalance(ctx context.Context, userID, delegationID string, balance int64) error
+	GetPoolBalance(ctx context.Context, userID, poolID string) (Amount, error)
+	UpdatePoolBalance(ctx context.Context, userID, poolID string, balance Amount) error
+	AddBalanceObservation(ctx context.Context, userID, kind, id string, observation BalanceObservation) error
+	GetBalanceObservations(ctx context.Context, userID, kind, id string) ([]BalanceObservation, error)
+	RemoveBalanceObservation(ctx context.Context, userID, kind, id string, height int64) error
+	SettleBalanceObservation(ctx context.Context, userID, kind, id string, height int64) error
+	GetPoolState(ctx context.Context, userID, poolID string) (PoolState, error)
+	UpdatePoolState(ctx context.Context, userID, poolID string, state PoolState) error
+	SetPoolBlockNotifications(ctx context.Context, userID, poolID string, enabled bool, fromHeight int64) error
+	GetBlockNotificationPools(ctx context.Context, userID string) (map[string]int64, error)
+	UpdatePoolLastBlock(ctx context.Context, userID, poolID string, height int64) error
+	SetPoolDelegationAlerts(ctx context.Context, userID, poolID string, enabled bool, threshold Amount, delegations map[string]Amount) error
+	GetDelegationAlertPools(ctx context.Context, userID string) (map[string]Amount, error)
+	GetPoolDelegationBalances(ctx context.Context, userID, poolID string) (map[string]Amount, error)
+	ReplacePoolDelegationBalances(ctx context.Context, userID, poolID string, delegations map[string]Amount) error
+	SetPoolLiveness(ctx context.Context, userID, poolID string, enabled bool, window time.Duration, since BlockInfo) error
+	GetPoolLiveness(ctx context.Context, userID string) ([]PoolLiveness, error)
+	UpdatePoolLiveness(ctx context.Context, userID, poolID string, height int64, lastBlockTime time.Time, alerted bool) error
+	AddPoolSample(ctx context.Context, sample PoolSample, minInterval time.Duration) error
+	GetPoolSamples(ctx context.Context, poolID string, since time.Time) ([]PoolSample, error)
+	PrunePoolSamples(ctx context.Context, before time.Time) error
+	SetUserCurrency(ctx context.Context, userID, currency string) error
+	GetUserCurrency(ctx context.Context, userID string) (string, error)
+	GetDelegationBalance(ctx context.Context, userID, delegationID string) (Amount, error)
+	UpdateDelegationBalance(ctx context.Context, userID, delegationID string, balance Amount) error
 	AddNotification(ctx context.Context, userID string, chatID int64) error
 	RemoveNotification(ctx context.Context, userID string, chatID int64) error
 	ReplaceNotificationsChannel(ctx context.Context, userID string, chatID int64) error
@@ -33,9 +71,38 @@ type SQLStore struct {
 	stmtRemoveNotificationsByChatID *sql.Stmt
 	stmtGetNotificationChatIDs      *sql.Stmt
 	stmtGetPools                    *sql.Stmt
+	stmtGetTrackedPools             *sql.Stmt
+	stmtGetTrackedNetwork           *sql.Stmt
+	stmtGetMonitoredAddresses       *sql.Stmt
+	stmtUpdateAddressBalance        *sql.Stmt
+	stmtUpdateAddressLastTx         *sql.Stmt
+	stmtGetTransferAlertAddresses   *sql.Stmt
+	stmtGetAddressTransfers         *sql.Stmt
+	stmtMarkAddressTransfer         *sql.Stmt
+	stmtGetAddressTokenBalances     *sql.Stmt
+	stmtUpdateAddressTokenBalance   *sql.Stmt
 	stmtGetDelegations              *sql.Stmt
+	stmtGetSyncedAddresses          *sql.Stmt
 	stmtGetPoolBalance              *sql.Stmt
 	stmtUpdatePoolBalance           *sql.Stmt
+	stmtAddBalanceObservation       *sql.Stmt
+	stmtGetBalanceObservations      *sql.Stmt
+	stmtRemoveBalanceObservation    *sql.Stmt
+	stmtGetPoolState                *sql.Stmt
+	stmtUpdatePoolState             *sql.Stmt
+	stmtSetPoolBlockNotifications   *sql.Stmt
+	stmtGetBlockNotificationPools   *sql.Stmt
+	stmtUpdatePoolLastBlock         *sql.Stmt
+	stmtGetDelegationAlertPools     *sql.Stmt
+	stmtGetPoolDelegationBalances   *sql.Stmt
+	stmtSetPoolLiveness             *sql.Stmt
+	stmtGetPoolLiveness             *sql.Stmt
+	stmtUpdatePoolLiveness          *sql.Stmt
+	stmtAddPoolSample               *sql.Stmt
+	stmtGetPoolSamples              *sql.Stmt
+	stmtPrunePoolSamples            *sql.Stmt
+	stmtSetUserCurrency             *sql.Stmt
+	stmtGetUserCurrency             *sql.Stmt
 	stmtGetDelegationBalance        *sql.Stmt
 	stmtUpdateDelegationBalance     *sql.Stmt
 }
@@ -72,23 +139,146 @@ func (s *SQLStore) prepareStatements() error {
 	if err != nil {
 		return err
 	}
+	s.stmtGetTrackedPools, err = s.db.Prepare("SELECT DISTINCT poolID FROM pools ORDER BY poolID")
+	if err != nil {
+		return err
+	}
+	s.stmtGetTrackedNetwork, err = s.db.Prepare(`SELECT network FROM pools WHERE poolID = ?
+		UNION ALL SELECT network FROM delegations WHERE delegationID = ?
+		UNION ALL SELECT network FROM addresses WHERE address = ?
+		UNION ALL SELECT network FROM synced_addresses WHERE address = ?
+		LIMIT 1`)
+	if err != nil {
+		return err
+	}
+	s.stmtGetMonitoredAddresses, err = s.db.Prepare("SELECT address, balance_atoms, notify_on_change, threshold, last_tx_id FROM addresses WHERE userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtUpdateAddressBalance, err = s.db.Prepare("UPDATE addresses SET balance_atoms = ? WHERE address = ? AND userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtUpdateAddressLastTx, err = s.db.Prepare("UPDATE addresses SET last_tx_id = ? WHERE address = ? AND userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtGetTransferAlertAddresses, err = s.db.Prepare("SELECT address FROM addresses WHERE userID = ? AND transfer_alerts = 1 ORDER BY address")
+	if err != nil {
+		return err
+	}
+	s.stmtGetAddressTransfers, err = s.db.Prepare("SELECT txID, confirmed FROM address_transfers WHERE userID = ? AND address = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtMarkAddressTransfer, err = s.db.Prepare(`INSERT INTO address_transfers (userID, address, txID, confirmed) VALUES (?, ?, ?, ?)
+		ON CONFLICT (userID, address, txID) DO UPDATE SET confirmed = MAX(confirmed, excluded.confirmed)`)
+	if err != nil {
+		return err
+	}
+	s.stmtGetAddressTokenBalances, err = s.db.Prepare("SELECT tokenID, balance_atoms FROM address_tokens WHERE userID = ? AND address = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtUpdateAddressTokenBalance, err = s.db.Prepare("INSERT INTO address_tokens (userID, address, tokenID, balance_atoms) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
 	s.stmtGetDelegations, err = s.db.Prepare("SELECT delegationID FROM delegations WHERE userID = ?")
 	if err != nil {
 		return err
 	}
-	s.stmtGetPoolBalance, err = s.db.Prepare("SELECT balance FROM pools WHERE userID = ? AND poolID = ?")
+	s.stmtGetSyncedAddresses, err = s.db.Prepare("SELECT address FROM synced_addresses WHERE userID = ? ORDER BY address")
+	if err != nil {
+		return err
+	}
+	s.stmtGetPoolBalance, err = s.db.Prepare("SELECT balance_atoms FROM pools WHERE userID = ? AND poolID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtUpdatePoolBalance, err = s.db.Prepare("UPDATE pools SET balance_atoms = ? WHERE poolID = ? AND userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtAddBalanceObservation, err = s.db.Prepare(`INSERT INTO balance_observations (userID, kind, entityID, height, block_id, balance_atoms) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(userID, kind, entityID, height) DO UPDATE SET block_id = excluded.block_id, balance_atoms = excluded.balance_atoms`)
 	if err != nil {
 		return err
 	}
-	s.stmtUpdatePoolBalance, err = s.db.Prepare("UPDATE pools SET balance = ? WHERE poolID = ? AND userID = ?")
+	s.stmtGetBalanceObservations, err = s.db.Prepare("SELECT height, block_id, balance_atoms, announced FROM balance_observations WHERE userID = ? AND kind = ? AND entityID = ? ORDER BY height")
 	if err != nil {
 		return err
 	}
-	s.stmtGetDelegationBalance, err = s.db.Prepare("SELECT balance FROM delegations WHERE userID = ? AND delegationID = ?")
+	s.stmtRemoveBalanceObservation, err = s.db.Prepare("DELETE FROM balance_observations WHERE userID = ? AND kind = ? AND entityID = ? AND height = ?")
 	if err != nil {
 		return err
 	}
-	s.stmtUpdateDelegationBalance, err = s.db.Prepare("UPDATE delegations SET balance = ? WHERE delegationID = ? AND userID = ?")
+	s.stmtGetPoolState, err = s.db.Prepare("SELECT state FROM pools WHERE userID = ? AND poolID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtUpdatePoolState, err = s.db.Prepare("UPDATE pools SET state = ? WHERE poolID = ? AND userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtSetPoolBlockNotifications, err = s.db.Prepare("UPDATE pools SET notify_blocks = ?, last_block_height = ? WHERE poolID = ? AND userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtGetBlockNotificationPools, err = s.db.Prepare("SELECT poolID, last_block_height FROM pools WHERE userID = ? AND notify_blocks = 1")
+	if err != nil {
+		return err
+	}
+	s.stmtUpdatePoolLastBlock, err = s.db.Prepare("UPDATE pools SET last_block_height = ? WHERE poolID = ? AND userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtGetDelegationAlertPools, err = s.db.Prepare("SELECT poolID, delegation_alert_atoms FROM pools WHERE userID = ? AND notify_delegations = 1")
+	if err != nil {
+		return err
+	}
+	s.stmtGetPoolDelegationBalances, err = s.db.Prepare("SELECT delegationID, balance_atoms FROM pool_delegations WHERE userID = ? AND poolID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtSetPoolLiveness, err = s.db.Prepare("UPDATE pools SET liveness_enabled = ?, liveness_window_minutes = ?, last_block_time = ?, liveness_height = ?, liveness_alerted = 0 WHERE poolID = ? AND userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtGetPoolLiveness, err = s.db.Prepare("SELECT poolID, liveness_window_minutes, liveness_height, last_block_time, liveness_alerted FROM pools WHERE userID = ? AND liveness_enabled = 1")
+	if err != nil {
+		return err
+	}
+	s.stmtUpdatePoolLiveness, err = s.db.Prepare("UPDATE pools SET liveness_height = ?, last_block_time = ?, liveness_alerted = ? WHERE poolID = ? AND userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtAddPoolSample, err = s.db.Prepare(`INSERT INTO pool_samples (poolID, observed_at, height, staker_balance_atoms, delegations_balance_atoms)
+		SELECT ?, ?, ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM pool_samples WHERE poolID = ? AND observed_at > ?)`)
+	if err != nil {
+		return err
+	}
+	s.stmtGetPoolSamples, err = s.db.Prepare("SELECT observed_at, height, staker_balance_atoms, delegations_balance_atoms FROM pool_samples WHERE poolID = ? AND observed_at >= ? ORDER BY observed_at")
+	if err != nil {
+		return err
+	}
+	s.stmtPrunePoolSamples, err = s.db.Prepare("DELETE FROM pool_samples WHERE observed_at < ?")
+	if err != nil {
+		return err
+	}
+	s.stmtSetUserCurrency, err = s.db.Prepare("INSERT INTO user_settings (userID, currency) VALUES (?, ?) ON CONFLICT (userID) DO UPDATE SET currency = excluded.currency")
+	if err != nil {
+		return err
+	}
+	s.stmtGetUserCurrency, err = s.db.Prepare("SELECT currency FROM user_settings WHERE userID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtGetDelegationBalance, err = s.db.Prepare("SELECT balance_atoms FROM delegations WHERE userID = ? AND delegationID = ?")
+	if err != nil {
+		return err
+	}
+	s.stmtUpdateDelegationBalance, err = s.db.Prepare("UPDATE delegations SET balance_atoms = ? WHERE delegationID = ? AND userID = ?")
 	if err != nil {
 		return err
 	}
@@ -111,20 +301,49 @@ func (s *SQLStore) Close() error {
 	closeStmt(s.stmtRemoveNotificationsByChatID)
 	closeStmt(s.stmtGetNotificationChatIDs)
 	closeStmt(s.stmtGetPools)
+	closeStmt(s.stmtGetTrackedPools)
+	closeStmt(s.stmtGetTrackedNetwork)
+	closeStmt(s.stmtGetMonitoredAddresses)
+	closeStmt(s.stmtUpdateAddressBalance)
+	closeStmt(s.stmtUpdateAddressLastTx)
+	closeStmt(s.stmtGetTransferAlertAddresses)
+	closeStmt(s.stmtGetAddressTransfers)
+	closeStmt(s.stmtMarkAddressTransfer)
+	closeStmt(s.stmtGetAddressTokenBalances)
+	closeStmt(s.stmtUpdateAddressTokenBalance)
 	closeStmt(s.stmtGetDelegations)
+	closeStmt(s.stmtGetSyncedAddresses)
 	closeStmt(s.stmtGetPoolBalance)
 	closeStmt(s.stmtUpdatePoolBalance)
+	closeStmt(s.stmtAddBalanceObservation)
+	closeStmt(s.stmtGetBalanceObservations)
+	closeStmt(s.stmtRemoveBalanceObservation)
+	closeStmt(s.stmtGetPoolState)
+	closeStmt(s.stmtUpdatePoolState)
+	closeStmt(s.stmtSetPoolBlockNotifications)
+	closeStmt(s.stmtGetBlockNotificationPools)
+	closeStmt(s.stmtUpdatePoolLastBlock)
+	closeStmt(s.stmtGetDelegationAlertPools)
+	closeStmt(s.stmtGetPoolDelegationBalances)
+	closeStmt(s.stmtSetPoolLiveness)
+	closeStmt(s.stmtGetPoolLiveness)
+	closeStmt(s.stmtUpdatePoolLiveness)
+	closeStmt(s.stmtAddPoolSample)
+	closeStmt(s.stmtGetPoolSamples)
+	closeStmt(s.stmtPrunePoolSamples)
+	closeStmt(s.stmtSetUserCurrency)
+	closeStmt(s.stmtGetUserCurrency)
 	closeStmt(s.stmtGetDelegationBalance)
 	closeStmt(s.stmtUpdateDelegationBalance)
 	return firstErr
 }
 
-func (s *SQLStore) AddMonitoredAddress(ctx context.Context, userID, address string, threshold int, notifyOnChange bool, chatID int64) error {
+func (s *SQLStore) AddMonitoredAddress(ctx context.Context, userID, address, network string, threshold int, notifyOnChange bool, chatID int64) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	_, err = tx.ExecContext(ctx, "INSERT INTO addresses (userID, address, threshold, notify_on_change) VALUES (?, ?, ?, ?)", userID, address, threshold, notifyOnChange)
+	_, err = tx.ExecContext(ctx, "INSERT INTO addresses (userID, address, network, threshold, notify_on_change) VALUES (?, ?, ?, ?, ?)", userID, address, network, threshold, notifyOnChange)
 	if err != nil {
 		_ = tx.Rollback()
 		return err
@@ -139,12 +358,191 @@ func (s *SQLStore) AddMonitoredAddress(ctx context.Context, userID, address stri
 	return tx.Commit()
 }
 
-func (s *SQLStore) AddPool(ctx context.Context, userID, poolID string) error {
-	return addPoolWithContext(ctx, s.db, userID, poolID)
+func (s *SQLStore) RemoveMonitoredAddress(ctx context.Context, userID, address string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM addresses WHERE userID = ? AND address = ?", userID, address); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM address_tokens WHERE userID = ? AND address = ?", userID, address); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM address_transfers WHERE userID = ? AND address = ?", userID, address); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) GetMonitoredAddresses(ctx context.Context, userID string) ([]MonitoredAddress, error) {
+	rows, err := s.stmtGetMonitoredAddresses.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []MonitoredAddress
+	for rows.Next() {
+		var address MonitoredAddress
+		// Rows from before thresholds were always set hold NULLs.
+		var notifyOnChange sql.NullBool
+		var threshold sql.NullInt64
+		if err := rows.Scan(&address.Address, &address.Balance, &notifyOnChange, &threshold, &address.LastTxID); err != nil {
+			return nil, err
+		}
+		address.NotifyOnChange = notifyOnChange.Bool
+		address.Threshold = int(threshold.Int64)
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+func (s *SQLStore) UpdateAddressBalance(ctx context.Context, userID, address string, balance Amount) error {
+	_, err := s.stmtUpdateAddressBalance.ExecContext(ctx, balance, address, userID)
+	return err
+}
+
+func (s *SQLStore) UpdateAddressLastTx(ctx context.Context, userID, address, txID string) error {
+	_, err := s.stmtUpdateAddressLastTx.ExecContext(ctx, txID, address, userID)
+	return err
+}
+
+// SetAddressTransferAlerts toggles transfer alerts for a monitored address.
+// The transactions in knownTxIDs count as already alerted, so only later
+// ones are announced. It returns sql.ErrNoRows if the user does not monitor
+// the address.
+func (s *SQLStore) SetAddressTransferAlerts(ctx context.Context, userID, address string, enabled bool, knownTxIDs []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE addresses SET transfer_alerts = ? WHERE address = ? AND userID = ?", enabled, address, userID)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if affected == 0 {
+		_ = tx.Rollback()
+		return sql.ErrNoRows
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM address_transfers WHERE userID = ? AND address = ?", userID, address); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if enabled {
+		stmt := tx.StmtContext(ctx, s.stmtMarkAddressTransfer)
+		for _, txID := range knownTxIDs {
+			if _, err := stmt.ExecContext(ctx, userID, address, txID, true); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) GetTransferAlertAddresses(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.stmtGetTransferAlertAddresses.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
+}
+
+// GetAddressTransfers returns the alerted transactions of an address, mapped
+// to whether their confirmation was announced too.
+func (s *SQLStore) GetAddressTransfers(ctx context.Context, userID, address string) (map[string]bool, error) {
+	rows, err := s.stmtGetAddressTransfers.QueryContext(ctx, userID, address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := make(map[string]bool)
+	for rows.Next() {
+		var txID string
+		var confirmed bool
+		if err := rows.Scan(&txID, &confirmed); err != nil {
+			return nil, err
+		}
+		transfers[txID] = confirmed
+	}
+	return transfers, rows.Err()
+}
+
+// MarkAddressTransfer records an alert for a transaction. A confirmation,
+// once recorded, is never undone, so a reorganised transaction is not
+// announced again.
+func (s *SQLStore) MarkAddressTransfer(ctx context.Context, userID, address, txID string, confirmed bool) error {
+	_, err := s.stmtMarkAddressTransfer.ExecContext(ctx, userID, address, txID, confirmed)
+	return err
+}
+
+func (s *SQLStore) GetAddressTokenBalances(ctx context.Context, userID, address string) (map[string]Amount, error) {
+	rows, err := s.stmtGetAddressTokenBalances.QueryContext(ctx, userID, address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[string]Amount)
+	for rows.Next() {
+		var tokenID string
+		var balance Amount
+		if err := rows.Scan(&tokenID, &balance); err != nil {
+			return nil, err
+		}
+		balances[tokenID] = balance
+	}
+	return balances, rows.Err()
+}
+
+func (s *SQLStore) UpdateAddressTokenBalance(ctx context.Context, userID, address, tokenID string, balance Amount) error {
+	_, err := s.stmtUpdateAddressTokenBalance.ExecContext(ctx, userID, address, tokenID, balance)
+	return err
+}
+
+func (s *SQLStore) AddPool(ctx context.Context, userID, poolID, network string) error {
+	return addPoolWithContext(ctx, s.db, userID, poolID, network)
 }
 
 func (s *SQLStore) RemovePool(ctx context.Context, userID, poolID string) error {
-	return removePoolWithContext(ctx, s.db, userID, poolID)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM pools WHERE userID = ? AND poolID = ?", userID, poolID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM pool_delegations WHERE userID = ? AND poolID = ?", userID, poolID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM balance_observations WHERE userID = ? AND kind = ? AND entityID = ?", userID, observedPool, poolID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *SQLStore) GetPools(ctx context.Context, userID string) ([]string, error) {
@@ -165,8 +563,87 @@ func (s *SQLStore) GetPools(ctx context.Context, userID string) ([]string, error
 	return pools, nil
 }
 
-func (s *SQLStore) AddDelegation(ctx context.Context, userID, delegationID string) error {
-	return addDelegationWithContext(ctx, s.db, userID, delegationID)
+// GetTrackedPools returns every pool tracked by any user.
+func (s *SQLStore) GetTrackedPools(ctx context.Context) ([]string, error) {
+	rows, err := s.stmtGetTrackedPools.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pools []string
+	for rows.Next() {
+		var poolID string
+		if err := rows.Scan(&poolID); err != nil {
+			return nil, err
+		}
+		pools = append(pools, poolID)
+	}
+	return pools, rows.Err()
+}
+
+// GetTrackedNetwork returns the network stored next to a tracked pool,
+// delegation or address, or sql.ErrNoRows if no user tracks id.
+func (s *SQLStore) GetTrackedNetwork(ctx context.Context, id string) (string, error) {
+	var network string
+	err := s.stmtGetTrackedNetwork.QueryRowContext(ctx, id, id, id, id).Scan(&network)
+	return network, err
+}
+
+func (s *SQLStore) AddDelegation(ctx context.Context, userID, delegationID, network string) error {
+	return addDelegationWithContext(ctx, s.db, userID, delegationID, network)
+}
+
+// ImportPoolsAndDelegations tracks all the given pools and delegations of
+// one network, or none of them if any insert fails.
+func (s *SQLStore) ImportPoolsAndDelegations(ctx context.Context, userID, network string, poolIDs, delegationIDs []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, poolID := range poolIDs {
+		if err := addPoolWithContext(ctx, tx, userID, poolID, network); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	for _, delegationID := range delegationIDs {
+		if err := addDelegationWithContext(ctx, tx, userID, delegationID, network); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetAddressSync toggles tracking new delegations of an address as they
+// appear.
+func (s *SQLStore) SetAddressSync(ctx context.Context, userID, address, network string, enabled bool) error {
+	var err error
+	if enabled {
+		_, err = s.db.ExecContext(ctx, "INSERT INTO synced_addresses (userID, address, network) VALUES (?, ?, ?)", userID, address, network)
+	} else {
+		_, err = s.db.ExecContext(ctx, "DELETE FROM synced_addresses WHERE userID = ? AND address = ?", userID, address)
+	}
+	return err
+}
+
+func (s *SQLStore) GetSyncedAddresses(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.stmtGetSyncedAddresses.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+		if err := rows.Scan(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, rows.Err()
 }
 
 func (s *SQLStore) RemoveDelegation(ctx context.Context, userID, delegationID string) error {
@@ -191,24 +668,325 @@ func (s *SQLStore) GetDelegations(ctx context.Context, userID string) ([]string,
 	return delegations, nil
 }
 
-func (s *SQLStore) GetPoolBalance(ctx context.Context, userID, poolID string) (int64, error) {
-	var balance int64
+func (s *SQLStore) GetPoolBalance(ctx context.Context, userID, poolID string) (Amount, error) {
+	var balance Amount
 	err := s.stmtGetPoolBalance.QueryRowContext(ctx, userID, poolID).Scan(&balance)
 	return balance, err
 }
 
-func (s *SQLStore) UpdatePoolBalance(ctx context.Context, userID, poolID string, balance int64) error {
+func (s *SQLStore) UpdatePoolBalance(ctx context.Context, userID, poolID string, balance Amount) error {
 	_, err := s.stmtUpdatePoolBalance.ExecContext(ctx, balance, poolID, userID)
 	return err
 }
 
-func (s *SQLStore) GetDelegationBalance(ctx context.Context, userID, delegationID string) (int64, error) {
-	var balance int64
+// AddBalanceObservation records a balance of a pool or delegation read at a
+// block height. A second observation at the same height replaces the first
+// and keeps whether it was announced.
+func (s *SQLStore) AddBalanceObservation(ctx context.Context, userID, kind, id string, observation BalanceObservation) error {
+	_, err := s.stmtAddBalanceObservation.ExecContext(ctx, userID, kind, id, observation.Height, observation.BlockID, observation.Balance)
+	return err
+}
+
+// GetBalanceObservations returns the kept observations of a pool or
+// delegation, lowest height first.
+func (s *SQLStore) GetBalanceObservations(ctx context.Context, userID, kind, id string) ([]BalanceObservation, error) {
+	rows, err := s.stmtGetBalanceObservations.QueryContext(ctx, userID, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var observations []BalanceObservation
+	for rows.Next() {
+		var observation BalanceObservation
+		if err := rows.Scan(&observation.Height, &observation.BlockID, &observation.Balance, &observation.Announced); err != nil {
+			return nil, err
+		}
+		observations = append(observations, observation)
+	}
+	return observations, rows.Err()
+}
+
+func (s *SQLStore) RemoveBalanceObservation(ctx context.Context, userID, kind, id string, height int64) error {
+	_, err := s.stmtRemoveBalanceObservation.ExecContext(ctx, userID, kind, id, height)
+	return err
+}
+
+// SettleBalanceObservation marks the observation at height as announced and
+// forgets the ones below it.
+func (s *SQLStore) SettleBalanceObservation(ctx context.Context, userID, kind, id string, height int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE balance_observations SET announced = 1 WHERE userID = ? AND kind = ? AND entityID = ? AND height = ?", userID, kind, id, height); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM balance_observations WHERE userID = ? AND kind = ? AND entityID = ? AND height < ?", userID, kind, id, height); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) GetPoolState(ctx context.Context, userID, poolID string) (PoolState, error) {
+	var key string
+	if err := s.stmtGetPoolState.QueryRowContext(ctx, userID, poolID).Scan(&key); err != nil {
+		return PoolStateUnknown, err
+	}
+	return parsePoolState(key)
+}
+
+func (s *SQLStore) UpdatePoolState(ctx context.Context, userID, poolID string, state PoolState) error {
+	_, err := s.stmtUpdatePoolState.ExecContext(ctx, state.storageKey(), poolID, userID)
+	return err
+}
+
+// SetPoolBlockNotifications toggles block production notifications for a
+// tracked pool; blocks up to fromHeight are treated as already announced. It
+// returns sql.ErrNoRows if the user does not track the pool.
+func (s *SQLStore) SetPoolBlockNotifications(ctx context.Context, userID, poolID string, enabled bool, fromHeight int64) error {
+	res, err := s.stmtSetPoolBlockNotifications.ExecContext(ctx, enabled, fromHeight, poolID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetBlockNotificationPools returns the pools with block notifications on,
+// mapped to the height of the last announced block.
+func (s *SQLStore) GetBlockNotificationPools(ctx context.Context, userID string) (map[string]int64, error) {
+	rows, err := s.stmtGetBlockNotificationPools.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pools := make(map[string]int64)
+	for rows.Next() {
+		var poolID string
+		var height int64
+		if err := rows.Scan(&poolID, &height); err != nil {
+			return nil, err
+		}
+		pools[poolID] = height
+	}
+	return pools, rows.Err()
+}
+
+func (s *SQLStore) UpdatePoolLastBlock(ctx context.Context, userID, poolID string, height int64) error {
+	_, err := s.stmtUpdatePoolLastBlock.ExecContext(ctx, height, poolID, userID)
+	return err
+}
+
+// SetPoolDelegationAlerts toggles delegation alerts for a tracked pool.
+// Withdrawals of at least threshold are announced, and delegations become
+// the pool's last seen ones so only later changes are. It returns
+// sql.ErrNoRows if the user does not track the pool.
+func (s *SQLStore) SetPoolDelegationAlerts(ctx context.Context, userID, poolID string, enabled bool, threshold Amount, delegations map[string]Amount) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE pools SET notify_delegations = ?, delegation_alert_atoms = ? WHERE poolID = ? AND userID = ?", enabled, threshold, poolID, userID)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if affected == 0 {
+		_ = tx.Rollback()
+		return sql.ErrNoRows
+	}
+	if err := replacePoolDelegationBalances(ctx, tx, userID, poolID, delegations); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetDelegationAlertPools returns the alert threshold of every pool the user
+// enabled delegation alerts for.
+func (s *SQLStore) GetDelegationAlertPools(ctx context.Context, userID string) (map[string]Amount, error) {
+	rows, err := s.stmtGetDelegationAlertPools.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pools := make(map[string]Amount)
+	for rows.Next() {
+		var poolID string
+		var threshold Amount
+		if err := rows.Scan(&poolID, &threshold); err != nil {
+			return nil, err
+		}
+		pools[poolID] = threshold
+	}
+	return pools, rows.Err()
+}
+
+func (s *SQLStore) GetPoolDelegationBalances(ctx context.Context, userID, poolID string) (map[string]Amount, error) {
+	rows, err := s.stmtGetPoolDelegationBalances.QueryContext(ctx, userID, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delegations := make(map[string]Amount)
+	for rows.Next() {
+		var delegationID string
+		var balance Amount
+		if err := rows.Scan(&delegationID, &balance); err != nil {
+			return nil, err
+		}
+		delegations[delegationID] = balance
+	}
+	return delegations, rows.Err()
+}
+
+// ReplacePoolDelegationBalances stores delegations as the pool's last seen
+// ones, dropping any others.
+func (s *SQLStore) ReplacePoolDelegationBalances(ctx context.Context, userID, poolID string, delegations map[string]Amount) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := replacePoolDelegationBalances(ctx, tx, userID, poolID, delegations); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func replacePoolDelegationBalances(ctx context.Context, tx *sql.Tx, userID, poolID string, delegations map[string]Amount) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM pool_delegations WHERE userID = ? AND poolID = ?", userID, poolID); err != nil {
+		return err
+	}
+	for delegationID, balance := range delegations {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO pool_delegations (userID, poolID, delegationID, balance_atoms) VALUES (?, ?, ?, ?)", userID, poolID, delegationID, balance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPoolLiveness turns the liveness alert of a tracked pool on or off and
+// restarts the watch at the since block. A zero window expects blocks at the
+// pool's stake share. It returns sql.ErrNoRows if the user does not track the
+// pool.
+func (s *SQLStore) SetPoolLiveness(ctx context.Context, userID, poolID string, enabled bool, window time.Duration, since BlockInfo) error {
+	res, err := s.stmtSetPoolLiveness.ExecContext(ctx, enabled, int64(window/time.Minute), since.Timestamp.Unix(), since.Height, poolID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *SQLStore) GetPoolLiveness(ctx context.Context, userID string) ([]PoolLiveness, error) {
+	rows, err := s.stmtGetPoolLiveness.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pools []PoolLiveness
+	for rows.Next() {
+		var pool PoolLiveness
+		var windowMinutes, lastBlockTime int64
+		if err := rows.Scan(&pool.PoolID, &windowMinutes, &pool.Height, &lastBlockTime, &pool.Alerted); err != nil {
+			return nil, err
+		}
+		pool.Window = time.Duration(windowMinutes) * time.Minute
+		pool.LastBlockTime = time.Unix(lastBlockTime, 0).UTC()
+		pools = append(pools, pool)
+	}
+	return pools, rows.Err()
+}
+
+func (s *SQLStore) UpdatePoolLiveness(ctx context.Context, userID, poolID string, height int64, lastBlockTime time.Time, alerted bool) error {
+	_, err := s.stmtUpdatePoolLiveness.ExecContext(ctx, height, lastBlockTime.Unix(), alerted, poolID, userID)
+	return err
+}
+
+// AddPoolSample stores a balance sample unless the pool already has one
+// taken less than minInterval before it.
+func (s *SQLStore) AddPoolSample(ctx context.Context, sample PoolSample, minInterval time.Duration) error {
+	observedAt := sample.ObservedAt.Unix()
+	_, err := s.stmtAddPoolSample.ExecContext(ctx, sample.PoolID, observedAt, sample.Height, sample.StakerBalance, sample.DelegationsBalance,
+		sample.PoolID, observedAt-int64(minInterval/time.Second))
+	return err
+}
+
+// GetPoolSamples returns the samples of a pool taken since the given time,
+// oldest first.
+func (s *SQLStore) GetPoolSamples(ctx context.Context, poolID string, since time.Time) ([]PoolSample, error) {
+	rows, err := s.stmtGetPoolSamples.QueryContext(ctx, poolID, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []PoolSample
+	for rows.Next() {
+		sample := PoolSample{PoolID: poolID}
+		var observedAt int64
+		if err := rows.Scan(&observedAt, &sample.Height, &sample.StakerBalance, &sample.DelegationsBalance); err != nil {
+			return nil, err
+		}
+		sample.ObservedAt = time.Unix(observedAt, 0).UTC()
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+func (s *SQLStore) PrunePoolSamples(ctx context.Context, before time.Time) error {
+	_, err := s.stmtPrunePoolSamples.ExecContext(ctx, before.Unix())
+	return err
+}
+
+// SetUserCurrency sets the fiat currency shown to a user, "" turning fiat
+// values off.
+func (s *SQLStore) SetUserCurrency(ctx context.Context, userID, currency string) error {
+	_, err := s.stmtSetUserCurrency.ExecContext(ctx, userID, currency)
+	return err
+}
+
+// GetUserCurrency returns the user's fiat currency, or "" if none was set.
+func (s *SQLStore) GetUserCurrency(ctx context.Context, userID string) (string, error) {
+	var currency string
+	err := s.stmtGetUserCurrency.QueryRowContext(ctx, userID).Scan(&currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return currency, err
+}
+
+func (s *SQLStore) GetDelegationBalance(ctx context.Context, userID, delegationID string) (Amount, error) {
+	var balance Amount
 	err := s.stmtGetDelegationBalance.QueryRowContext(ctx, userID, delegationID).Scan(&balance)
 	return balance, err
 }
 
-func (s *SQLStore) UpdateDelegationBalance(ctx context.Context, userID, delegationID string, balance int64) error {
+func (s *SQLStore) UpdateDelegationBalance(ctx context.Context, userID, delegationID string, balance Amount) error {
 	_, err := s.stmtUpdateDelegationBalance.ExecContext(ctx, balance, delegationID, userID)
 	return err
 }
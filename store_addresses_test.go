@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSQLStoreMonitoredAddresses(t *testing.T) {
+	store, exec := newTestSQLStore(t)
+	ctx := context.Background()
+
+	if err := store.AddMonitoredAddress(ctx, "u1", "a1", mainnetNetwork, 25, false, 1); err != nil {
+		t.Fatalf("AddMonitoredAddress failed: %v", err)
+	}
+	if err := store.UpdateAddressBalance(ctx, "u1", "a1", AmountFromAtoms(42)); err != nil {
+		t.Fatalf("UpdateAddressBalance failed: %v", err)
+	}
+
+	addresses, err := store.GetMonitoredAddresses(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetMonitoredAddresses failed: %v", err)
+	}
+	if len(addresses) != 1 {
+		t.Fatalf("expected 1 address, got %d", len(addresses))
+	}
+	got := addresses[0]
+	if got.Address != "a1" || got.Threshold != 25 || got.NotifyOnChange || got.Balance.Cmp(AmountFromAtoms(42)) != 0 {
+		t.Fatalf("unexpected address: %+v", got)
+	}
+
+	if err := store.RemoveMonitoredAddress(ctx, "u1", "a1"); err != nil {
+		t.Fatalf("RemoveMonitoredAddress failed: %v", err)
+	}
+	addresses, err = store.GetMonitoredAddresses(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetMonitoredAddresses failed: %v", err)
+	}
+	if len(addresses) != 0 {
+		t.Fatalf("expected no addresses after removal, got %v", addresses)
+	}
+
+	// Old rows may lack the threshold and notification mode.
+	exec("INSERT INTO addresses (userID, address, notify_on_change, threshold) VALUES ('u1', 'a2', NULL, NULL)")
+	addresses, err = store.GetMonitoredAddresses(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetMonitoredAddresses failed on NULL columns: %v", err)
+	}
+	if len(addresses) != 1 || addresses[0].Threshold != 0 || addresses[0].NotifyOnChange {
+		t.Fatalf("unexpected address with NULL columns: %+v", addresses)
+	}
+}
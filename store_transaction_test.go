@@ -29,7 +29,7 @@ func TestAddMonitoredAddressTransactional(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err = store.AddMonitoredAddress(ctx, "user-1", "addr-1", 0, true, 10)
+	err = store.AddMonitoredAddress(ctx, "user-1", "addr-1", mainnetNetwork, 0, true, 10)
 	if err == nil {
 		t.Fatal("expected error when notifications table is missing")
 	}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-telegram/bot"
@@ -22,10 +27,17 @@ func (a *App) registerHandlers() {
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pool_add", bot.MatchTypeContains, a.addPoolHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pool_remove", bot.MatchTypeContains, a.removePoolHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pool_list", bot.MatchTypeContains, a.listPoolHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pool_info", bot.MatchTypeContains, a.poolInfoHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pool_blocks", bot.MatchTypeContains, a.poolBlocksHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pool_liveness", bot.MatchTypeContains, a.poolLivenessHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pool_stats", bot.MatchTypeContains, a.poolStatsHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/pool_delegations", bot.MatchTypeContains, a.poolDelegationsHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/delegation_add", bot.MatchTypeContains, a.addDelegationHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/delegation_remove", bot.MatchTypeContains, a.removeDelegationHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/delegation_list", bot.MatchTypeContains, a.listDelegationsHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/delegation_info", bot.MatchTypeContains, a.delegationInfoHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/balance", bot.MatchTypeContains, a.balanceHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/currency", bot.MatchTypeContains, a.currencyHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/notify_start", bot.MatchTypeContains, a.notifyStartHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/notify_stop", bot.MatchTypeContains, a.notifyStopHanlder)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/notify_status", bot.MatchTypeContains, a.notifyStatusHandler)
@@ -33,20 +45,40 @@ func (a *App) registerHandlers() {
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/debug_status", bot.MatchTypeContains, a.debugStatusHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/debug_stop", bot.MatchTypeContains, a.debugStopHandler)
 	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/debug_start", bot.MatchTypeContains, a.debugStartHandler)
-	//	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/address_add", bot.MatchTypeContains, a.addressAddHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/debug_api", bot.MatchTypeContains, a.debugAPIHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/address_add", bot.MatchTypeContains, a.addressAddHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/address_remove", bot.MatchTypeContains, a.addressRemoveHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/address_list", bot.MatchTypeContains, a.addressListHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/address_history", bot.MatchTypeContains, a.addressHistoryHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/address_transfers", bot.MatchTypeContains, a.addressTransfersHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/tokens", bot.MatchTypeContains, a.tokensHandler)
+	a.bot.RegisterHandler(bot.HandlerTypeMessageText, "/import_address", bot.MatchTypeContains, a.importAddressHandler)
 }
 
 func (a *App) helloHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	helpMessage := "Available commands:\n"
 	helpMessage += "`/help` : *this help message* \n"
-	//	helpMessage += "`/address_add <address> [threshold] ` : *Add a new address for monitoring*\n"
 	helpMessage += "`/pool_add <poolID> ` : *Add a pool*\n"
 	helpMessage += "`/pool_remove <poolID> ` : *Remove a pool*\n"
 	helpMessage += "`/pool_list ` : *List your pools*\n"
+	helpMessage += "`/pool_info <poolID> ` : *Show pool details*\n"
+	helpMessage += "`/pool_blocks <poolID> on|off ` : *Notify when the pool produces a block*\n"
+	helpMessage += "`/pool_liveness <poolID> on|<hours>|off ` : *Alert when the pool produces no block for longer than its stake share makes likely, or for that many hours*\n"
+	helpMessage += "`/pool_stats <poolID> ` : *Estimate the pool's rewards and yield*\n"
+	helpMessage += "`/pool_delegations <poolID> [notify <ML>|off] ` : *List the pool's delegators; alert on new delegations and withdrawals of at least that many ML*\n"
 	helpMessage += "`/delegation_add <delegationID> ` : *Add a delegation*\n"
 	helpMessage += "`/delegation_remove <delegationID> ` : *Remove a delegation*\n"
 	helpMessage += "`/delegation_list ` : *List your delegations*\n"
+	helpMessage += "`/delegation_info <delegationID> ` : *Show delegation details and its pool state*\n"
+	helpMessage += "`/address_add <address> [threshold] ` : *Monitor an address; with a threshold only changes of at least that many ML are notified*\n"
+	helpMessage += "`/address_remove <address> ` : *Stop monitoring an address*\n"
+	helpMessage += "`/address_list ` : *List your monitored addresses*\n"
+	helpMessage += "`/address_history <address> [page] ` : *List the address's transactions, newest first*\n"
+	helpMessage += "`/address_transfers <address> on|off ` : *Alert on each transaction of a monitored address, in the mempool and once confirmed*\n"
+	helpMessage += "`/tokens ` : *List token holdings across your monitored addresses*\n"
+	helpMessage += "`/import_address <address> [on|off] ` : *Track the pools and delegations owned by an address; `on` keeps adding new delegations, `off` stops*\n"
 	helpMessage += "`/balance ` : *Get the total balance of your pools*\n"
+	helpMessage += "`/currency [<code>|off] ` : *Show ML amounts also in a fiat currency, e.g. USD*\n"
 	helpMessage += "`/notify_start ` : *Notify on balance change*\n"
 	helpMessage += "`/notify_stop ` : *Stop balance change notifications*\n"
 	helpMessage += "`/notify_status ` : *Check if you're subscribed to balance change notifications*\n"
@@ -56,6 +88,7 @@ func (a *App) helloHandler(ctx context.Context, b *bot.Bot, update *models.Updat
 		helpMessage += "`/debug_status <user_id>` : *Admin only: notification status*\n"
 		helpMessage += "`/debug_stop <user_id>` : *Admin only: stop notifications*\n"
 		helpMessage += "`/debug_start <user_id> [chat_id]` : *Admin only: start notifications*\n"
+		helpMessage += "`/debug_api` : *Admin only: API server health, the endpoint in use and cache counters*\n"
 	}
 
 	a.sendMessage(ctx, b, update.Message.Chat.ID, helpMessage)
@@ -65,24 +98,24 @@ func (a *App) addressAddHandler(ctx context.Context, b *bot.Bot, update *models.
 	userID := update.Message.From.ID
 	chatID := update.Message.Chat.ID
 	parts := strings.Fields(update.Message.Text)
-	if len(parts) == 0 {
+	if len(parts) < 2 {
 		a.sendMessage(ctx, b, chatID, "Usage: `/address_add <address> [threshold]`")
 		return
 	}
 
-	address := parts[1]
-	if !validateBech32Address(address) {
-		a.sendMessage(ctx, b, chatID, "Invalid address")
+	parsed, err := a.networks.ParseAddress(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, chatID, "Invalid address: "+err.Error())
 		return
 	}
+	address, network := parsed.String(), parsed.Network
 	var threshold int
 	var notifyOnChange bool = true
 
 	if len(parts) > 2 {
-		var err error
 		threshold, err = strconv.Atoi(parts[2])
-		if err != nil {
-			log.Printf("Invalid threshold provided, defaulting to notify on change: %v", err)
+		if err != nil || threshold < 0 {
+			log.Printf("Invalid threshold provided: %v", parts[2])
 			a.sendMessage(ctx, b, chatID, "Usage: `/address_add <address> [threshold]`")
 			return
 		} else {
@@ -90,7 +123,7 @@ func (a *App) addressAddHandler(ctx context.Context, b *bot.Bot, update *models.
 		}
 	}
 
-	err := a.store.AddMonitoredAddress(ctx, fmt.Sprint(userID), address, threshold, notifyOnChange, update.Message.Chat.ID)
+	err = a.store.AddMonitoredAddress(ctx, fmt.Sprint(userID), address, network.Name, threshold, notifyOnChange, update.Message.Chat.ID)
 	if err != nil {
 		log.Printf("Error adding monitored address: %v", err)
 		a.sendCommandError(ctx, b, chatID)
@@ -99,6 +132,366 @@ func (a *App) addressAddHandler(ctx context.Context, b *bot.Bot, update *models.
 	a.sendMessage(ctx, b, chatID, fmt.Sprintf("`%s` added for monitoring, ensure you start notifications with `/notify_start`", address))
 }
 
+func (a *App) addressRemoveHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := update.Message.From.ID
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Usage: `/address_remove <address>`")
+		return
+	}
+
+	address, err := a.networks.ParseAddress(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid address: "+err.Error())
+		return
+	}
+	err = a.store.RemoveMonitoredAddress(ctx, fmt.Sprint(userID), address.String())
+	if err != nil {
+		log.Printf("Error removing monitored address: %v", err)
+		a.sendCommandError(ctx, b, update.Message.Chat.ID)
+	} else {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Address removed")
+	}
+}
+
+func (a *App) addressListHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := update.Message.From.ID
+
+	addresses, err := a.store.GetMonitoredAddresses(ctx, fmt.Sprint(userID))
+	if err != nil {
+		log.Printf("Error listing addresses: %v", err)
+		a.sendCommandError(ctx, b, update.Message.Chat.ID)
+		return
+	}
+	if len(addresses) == 0 {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "You have no monitored addresses")
+		return
+	}
+
+	ids := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		ids = append(ids, address.Address)
+	}
+	results := fetchAll(ids, func(address string) (Amount, error) {
+		balance, err := a.client.GetAddressBalance(ctx, address)
+		return balance.Coins, err
+	})
+	if err := blockingLookupError(results); err != nil {
+		log.Printf("Error getting address balance: %v", err)
+		a.sendAPIError(ctx, b, update.Message.Chat.ID, err)
+		return
+	}
+
+	addressMessage := "Your addresses:\n"
+	for _, address := range addresses {
+		mode := "on change"
+		if !address.NotifyOnChange {
+			mode = fmt.Sprintf("changes of at least %v", a.formatML(AmountFromML(int64(address.Threshold))))
+		}
+		result := results[address.Address]
+		if result.err != nil {
+			log.Printf("Error getting balance of address %s: %v", address.Address, result.err)
+			addressMessage += fmt.Sprintf("`%v`%s: `%v` (%s) \n", address.Address, a.networkLabel(ctx, address.Address), lookupErrorLabel(result.err), mode)
+		} else {
+			addressMessage += fmt.Sprintf("`%v`%s: %v (%s) \n", address.Address, a.networkLabel(ctx, address.Address), a.formatML(result.value), mode)
+		}
+	}
+	a.sendLongMessage(ctx, b, update.Message.Chat.ID, addressMessage)
+}
+
+func (a *App) addressHistoryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		a.sendMessage(ctx, b, chatID, "Usage: `/address_history <address> [page]`")
+		return
+	}
+
+	parsed, err := a.networks.ParseAddress(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, chatID, "Invalid address: "+err.Error())
+		return
+	}
+	address := parsed.String()
+	page := 1
+	if len(parts) > 2 {
+		page, err = strconv.Atoi(parts[2])
+		if err != nil || page < 1 {
+			a.sendMessage(ctx, b, chatID, "Usage: `/address_history <address> [page]`")
+			return
+		}
+	}
+
+	history, err := a.client.GetAddressHistory(ctx, address, (page-1)*historyPageSize, historyPageSize)
+	if err != nil {
+		log.Printf("Error getting history of address %s: %v", address, err)
+		a.sendAPIError(ctx, b, chatID, err)
+		return
+	}
+	pages := (history.Total + historyPageSize - 1) / historyPageSize
+	if history.Total == 0 {
+		a.sendMessage(ctx, b, chatID, fmt.Sprintf("`%s` has no transactions", address))
+		return
+	}
+	if page > pages {
+		a.sendMessage(ctx, b, chatID, fmt.Sprintf("`%s` has only %d pages of transactions", address, pages))
+		return
+	}
+
+	msg := fmt.Sprintf("Transactions of `%s` (page %d of %d):\n", address, page, pages)
+	for _, tx := range history.Transactions {
+		msg += fmt.Sprintf("`%s`: %s, %s\n", tx.TxID, a.formatTransfer(tx, address), formatTxTime(tx))
+	}
+	if page < pages {
+		msg += fmt.Sprintf("Next page: `/address_history %s %d`\n", address, page+1)
+	}
+	a.sendLongMessage(ctx, b, chatID, msg)
+}
+
+func (a *App) addressTransfersHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := fmt.Sprint(update.Message.From.ID)
+	chatID := update.Message.Chat.ID
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 3 || (parts[2] != "on" && parts[2] != "off") {
+		a.sendMessage(ctx, b, chatID, "Usage: `/address_transfers <address> on|off`")
+		return
+	}
+
+	parsed, err := a.networks.ParseAddress(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, chatID, "Invalid address: "+err.Error())
+		return
+	}
+	address := parsed.String()
+	enabled := parts[2] == "on"
+
+	// Only transactions made from now on are alerted.
+	var known []string
+	if enabled {
+		balance, err := a.client.GetAddressBalance(ctx, address)
+		if err != nil {
+			log.Printf("Error getting address balance: %v", err)
+			a.sendAPIError(ctx, b, chatID, err)
+			return
+		}
+		known = balance.TransactionIDs
+	}
+	err = a.store.SetAddressTransferAlerts(ctx, userID, address, enabled, known)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		a.sendMessage(ctx, b, chatID, "You are not monitoring this address. Add it with `/address_add <address>` first.")
+	case err != nil:
+		log.Printf("Error updating transfer alerts: %v", err)
+		a.sendCommandError(ctx, b, chatID)
+	case enabled:
+		a.sendMessage(ctx, b, chatID, fmt.Sprintf("Transfer alerts enabled for `%s`: each new transaction is announced when it reaches the mempool and when it is confirmed", address))
+	default:
+		a.sendMessage(ctx, b, chatID, fmt.Sprintf("Transfer alerts disabled for `%s`", address))
+	}
+}
+
+// formatTransfer describes what tx did to address, e.g. "received 10 ML from
+// `tmt1…`".
+func (a *App) formatTransfer(tx TransactionInfo, address string) string {
+	delta, counterparties := tx.transferFor(address)
+	var msg string
+	switch {
+	case delta.Sign() > 0:
+		msg = "received " + a.formatML(delta)
+		if len(counterparties) > 0 {
+			msg += " from " + formatCounterparties(counterparties)
+		}
+	case delta.Sign() < 0:
+		msg = "sent " + a.formatML(delta.Abs())
+		if len(counterparties) > 0 {
+			msg += " to " + formatCounterparties(counterparties)
+		}
+	default:
+		msg = "no ML moved"
+	}
+	return msg
+}
+
+func formatTxTime(tx TransactionInfo) string {
+	if tx.BlockHeight == 0 {
+		return "in mempool"
+	}
+	return fmt.Sprintf("block %d, %s", tx.BlockHeight, tx.Timestamp.Format("2006-01-02 15:04:05 UTC"))
+}
+
+func (a *App) tokensHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := update.Message.From.ID
+
+	addresses, err := a.store.GetMonitoredAddresses(ctx, fmt.Sprint(userID))
+	if err != nil {
+		log.Printf("Error listing addresses: %v", err)
+		a.sendCommandError(ctx, b, update.Message.Chat.ID)
+		return
+	}
+	if len(addresses) == 0 {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "You have no monitored addresses")
+		return
+	}
+
+	ids := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		ids = append(ids, address.Address)
+	}
+	results := fetchAll(ids, func(address string) (AddressBalance, error) {
+		return a.client.GetAddressBalance(ctx, address)
+	})
+	if err := blockingLookupError(results); err != nil {
+		log.Printf("Error getting address balance: %v", err)
+		a.sendAPIError(ctx, b, update.Message.Chat.ID, err)
+		return
+	}
+
+	totals := make(map[string]Amount)
+	unavailable := 0
+	for address, result := range results {
+		if result.err != nil {
+			log.Printf("Error getting balance of address %s: %v", address, result.err)
+			unavailable++
+			continue
+		}
+		for tokenID, amount := range result.value.Tokens {
+			totals[tokenID] = totals[tokenID].Add(amount)
+		}
+	}
+
+	tokenIDs := make([]string, 0, len(totals))
+	for tokenID, amount := range totals {
+		if !amount.IsZero() {
+			tokenIDs = append(tokenIDs, tokenID)
+		}
+	}
+	infos := fetchAll(tokenIDs, func(tokenID string) (TokenInfo, error) {
+		return a.tokenInfo(ctx, tokenID)
+	})
+	holdings := make([]TokenInfo, 0, len(tokenIDs))
+	for _, tokenID := range tokenIDs {
+		info := infos[tokenID]
+		if info.err != nil {
+			// Without metadata the amount can only be shown in atoms.
+			log.Printf("Error getting token %s: %v", tokenID, info.err)
+			holdings = append(holdings, TokenInfo{TokenID: tokenID})
+			continue
+		}
+		holdings = append(holdings, info.value)
+	}
+	sort.Slice(holdings, func(i, j int) bool {
+		return holdings[i].DisplayTicker() < holdings[j].DisplayTicker()
+	})
+
+	var msg string
+	if len(holdings) == 0 {
+		msg = "No token holdings found\n"
+	} else {
+		msg = "Your tokens:\n"
+		for _, info := range holdings {
+			msg += fmt.Sprintf("`%s`: %s \n", info.DisplayTicker(), totals[info.TokenID].FormatUnits(info.Decimals, info.Decimals))
+		}
+	}
+	if unavailable > 0 {
+		msg += fmt.Sprintf("`%d` addresses unavailable\n", unavailable)
+	}
+	a.sendLongMessage(ctx, b, update.Message.Chat.ID, msg)
+}
+
+func (a *App) importAddressHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := fmt.Sprint(update.Message.From.ID)
+	chatID := update.Message.Chat.ID
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 || (len(parts) > 2 && parts[2] != "on" && parts[2] != "off") {
+		a.sendMessage(ctx, b, chatID, "Usage: `/import_address <address> [on|off]`")
+		return
+	}
+
+	parsed, err := a.networks.ParseAddress(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, chatID, "Invalid address: "+err.Error())
+		return
+	}
+	address, network := parsed.String(), parsed.Network
+	if len(parts) > 2 && parts[2] == "off" {
+		if err := a.store.SetAddressSync(ctx, userID, address, network.Name, false); err != nil {
+			log.Printf("Error updating address sync: %v", err)
+			a.sendCommandError(ctx, b, chatID)
+			return
+		}
+		a.sendMessage(ctx, b, chatID, fmt.Sprintf("New delegations of `%s` are no longer added", address))
+		return
+	}
+
+	delegations, err := a.client.GetAddressDelegations(ctx, address)
+	if err != nil {
+		log.Printf("Error getting delegations of %s: %v", address, err)
+		a.sendAPIError(ctx, b, chatID, err)
+		return
+	}
+	pools, err := a.client.GetAddressPools(ctx, address)
+	if err != nil {
+		log.Printf("Error getting pools of %s: %v", address, err)
+		a.sendAPIError(ctx, b, chatID, err)
+		return
+	}
+	trackedPools, err := a.store.GetPools(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting pools: %v", err)
+		a.sendCommandError(ctx, b, chatID)
+		return
+	}
+	trackedDelegations, err := a.store.GetDelegations(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting delegations: %v", err)
+		a.sendCommandError(ctx, b, chatID)
+		return
+	}
+
+	msg := ""
+	var newPools, newDelegations []string
+	for _, pool := range pools {
+		if pool.State != PoolStateActive {
+			continue
+		}
+		if slices.Contains(trackedPools, pool.PoolID) {
+			msg += fmt.Sprintf("Pool `%s` (already tracked)\n", pool.PoolID)
+			continue
+		}
+		newPools = append(newPools, pool.PoolID)
+		msg += fmt.Sprintf("Pool `%s`: %v\n", pool.PoolID, a.formatML(pool.StakerBalance))
+	}
+	for _, delegation := range fundedDelegations(delegations) {
+		if slices.Contains(trackedDelegations, delegation.DelegationID) {
+			msg += fmt.Sprintf("Delegation `%s` (already tracked)\n", delegation.DelegationID)
+			continue
+		}
+		newDelegations = append(newDelegations, delegation.DelegationID)
+		msg += fmt.Sprintf("Delegation `%s`: %v\n", delegation.DelegationID, a.formatML(delegation.Balance))
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("No pools or delegations found for `%s`\n", address)
+	} else {
+		msg = fmt.Sprintf("Found for `%s`:\n", address) + msg
+	}
+
+	if err := a.store.ImportPoolsAndDelegations(ctx, userID, network.Name, newPools, newDelegations); err != nil {
+		log.Printf("Error importing pools and delegations: %v", err)
+		a.sendCommandError(ctx, b, chatID)
+		return
+	}
+	msg += fmt.Sprintf("Added `%d` pools and `%d` delegations", len(newPools), len(newDelegations))
+	if len(parts) > 2 {
+		if err := a.store.SetAddressSync(ctx, userID, address, network.Name, true); err != nil {
+			log.Printf("Error updating address sync: %v", err)
+			a.sendCommandError(ctx, b, chatID)
+			return
+		}
+		msg += "\nNew delegations of this address will be added while notifications are on"
+	}
+	a.sendLongMessage(ctx, b, chatID, msg)
+}
+
 func (a *App) addPoolHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	log.Println("addPoolHandler")
 	userID := update.Message.From.ID
@@ -109,13 +502,13 @@ func (a *App) addPoolHandler(ctx context.Context, b *bot.Bot, update *models.Upd
 		return
 	}
 
-	poolID := parts[1]
-	if !validateBech32Address(poolID) {
-		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid pool ID")
+	poolID, err := a.networks.ParsePoolID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid pool ID: "+err.Error())
 		return
 	}
 
-	err := a.store.AddPool(ctx, fmt.Sprint(userID), poolID)
+	err = a.store.AddPool(ctx, fmt.Sprint(userID), poolID.String(), poolID.Network.Name)
 	if err != nil {
 		log.Printf("Error adding pool: %v", err)
 		a.sendCommandError(ctx, b, update.Message.Chat.ID)
@@ -132,12 +525,12 @@ func (a *App) removePoolHandler(ctx context.Context, b *bot.Bot, update *models.
 		return
 	}
 
-	poolID := parts[1]
-	if !validateBech32Address(poolID) {
-		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid pool ID")
+	poolID, err := a.networks.ParsePoolID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid pool ID: "+err.Error())
 		return
 	}
-	err := a.store.RemovePool(ctx, fmt.Sprint(userID), poolID)
+	err = a.store.RemovePool(ctx, fmt.Sprint(userID), poolID.String())
 	if err != nil {
 		log.Printf("Error removing pool: %v", err)
 		a.sendCommandError(ctx, b, update.Message.Chat.ID)
@@ -148,7 +541,6 @@ func (a *App) removePoolHandler(ctx context.Context, b *bot.Bot, update *models.
 
 func (a *App) listPoolHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	userID := update.Message.From.ID
-	p := message.NewPrinter(language.AmericanEnglish)
 
 	pools, err := a.store.GetPools(ctx, fmt.Sprint(userID))
 	if err != nil {
@@ -158,21 +550,30 @@ func (a *App) listPoolHandler(ctx context.Context, b *bot.Bot, update *models.Up
 		if len(pools) == 0 {
 			a.sendMessage(ctx, b, update.Message.Chat.ID, "You have no pools")
 		} else {
-			balances, err := runFetchMapWithLimit(pools, 10, func(poolID string) (int64, error) {
-				return a.client.GetPoolBalance(poolID)
+			results := fetchAll(pools, func(poolID string) (PoolInfo, error) {
+				return a.client.GetPoolInfo(ctx, poolID)
 			})
-			if err != nil {
+			if err := blockingLookupError(results); err != nil {
 				log.Printf("Error getting pool balance: %v", err)
-				a.sendCommandError(ctx, b, update.Message.Chat.ID)
+				a.sendAPIError(ctx, b, update.Message.Chat.ID, err)
 				return
 			}
+			rate := a.fiatRateFor(ctx, fmt.Sprint(userID))
 			poolMessage := "Your pools:\n"
 			for _, poolID := range pools {
-				balance := balances[poolID]
-				if balance == 0 {
-					poolMessage += p.Sprintf("`%v`: `decommissioned` \n", poolID)
-				} else {
-					poolMessage += p.Sprintf("`%v`: %v ML \n", poolID, balance)
+				result := results[poolID]
+				label := a.networkLabel(ctx, poolID)
+				switch {
+				case result.err != nil:
+					log.Printf("Error getting status of pool %s: %v", poolID, result.err)
+					poolMessage += fmt.Sprintf("`%v`%s: `%v` \n", poolID, label, lookupErrorLabel(result.err))
+				case result.value.State == PoolStateActive:
+					poolMessage += fmt.Sprintf("`%v`%s: %v%v \n", poolID, label, a.formatML(result.value.StakerBalance), rate.suffix(result.value.StakerBalance))
+					if estimate, ok := a.poolRewardEstimate(ctx, result.value); ok {
+						poolMessage += fmt.Sprintf("  ~%.1f blocks/day, APY ~%s, delegators ~%s\n", estimate.BlocksPerDay, formatYield(estimate.PoolAPY), formatYield(estimate.DelegatorAPY))
+					}
+				default:
+					poolMessage += fmt.Sprintf("`%v`%s: `%v` \n", poolID, label, result.value.State)
 				}
 			}
 			a.sendLongMessage(ctx, b, update.Message.Chat.ID, poolMessage)
@@ -180,6 +581,376 @@ func (a *App) listPoolHandler(ctx context.Context, b *bot.Bot, update *models.Up
 	}
 }
 
+func (a *App) poolInfoHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Usage: `/pool_info <poolID>`")
+		return
+	}
+
+	parsed, err := a.networks.ParsePoolID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid pool ID: "+err.Error())
+		return
+	}
+	poolID := parsed.String()
+
+	info, err := a.client.GetPoolInfo(ctx, poolID)
+	if err != nil {
+		log.Printf("Error getting pool info: %v", err)
+		a.sendAPIError(ctx, b, update.Message.Chat.ID, err)
+		return
+	}
+	a.sendLongMessage(ctx, b, update.Message.Chat.ID, a.formatPoolInfo(info))
+}
+
+func (a *App) poolStatsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := update.Message.From.ID
+	chatID := update.Message.Chat.ID
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		a.sendMessage(ctx, b, chatID, "Usage: `/pool_stats <poolID>`")
+		return
+	}
+
+	parsed, err := a.networks.ParsePoolID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, chatID, "Invalid pool ID: "+err.Error())
+		return
+	}
+	poolID := parsed.String()
+
+	info, err := a.client.GetPoolInfo(ctx, poolID)
+	if err != nil {
+		log.Printf("Error getting pool info: %v", err)
+		a.sendAPIError(ctx, b, chatID, err)
+		return
+	}
+	if info.State != PoolStateActive {
+		a.sendMessage(ctx, b, chatID, fmt.Sprintf("Pool `%s` is `%v`", poolID, info.State))
+		return
+	}
+	estimate, ok := a.poolRewardEstimate(ctx, info)
+	if !ok {
+		a.sendMessage(ctx, b, chatID, fmt.Sprintf("Not enough balance history for `%s` yet. Tracked pools are sampled hourly, check back later.", poolID))
+		return
+	}
+
+	msg := fmt.Sprintf("Pool `%s`, estimated from %s of history:\n", poolID, formatGap(estimate.Span))
+	msg += fmt.Sprintf("Blocks: ~%.1f per day\n", estimate.BlocksPerDay)
+	msg += fmt.Sprintf("Rewards: ~%v per day\n", a.formatEstimateML(AmountFromMLFloat(estimate.RewardPerDay)))
+	msg += fmt.Sprintf("Pool APY: ~%s\n", formatYield(estimate.PoolAPY))
+	msg += fmt.Sprintf("Delegator APY: ~%s after %s margin and %v cost per block\n",
+		formatYield(estimate.DelegatorAPY), formatPerThousand(info.MarginRatioPerThousand), a.formatML(info.CostPerBlock))
+
+	delegations, err := a.store.GetDelegations(ctx, fmt.Sprint(userID))
+	if err != nil {
+		log.Printf("Error getting delegations: %v", err)
+	}
+	results := fetchAll(delegations, func(delegationID string) (DelegationInfo, error) {
+		return a.client.GetDelegationInfo(ctx, delegationID)
+	})
+	var own string
+	for _, delegationID := range delegations {
+		result := results[delegationID]
+		if result.err != nil || result.value.PoolID != poolID {
+			continue
+		}
+		own += fmt.Sprintf("`%s`: %v, ~%v per day\n", delegationID, a.formatML(result.value.Balance),
+			a.formatEstimateML(estimate.DelegationRewardPerDay(result.value.Balance)))
+	}
+	if own != "" {
+		msg += "Your delegations:\n" + own
+	}
+	a.sendLongMessage(ctx, b, chatID, msg)
+}
+
+// poolRewardEstimate estimates a pool's rewards from its stored samples,
+// with info as the latest one.
+func (a *App) poolRewardEstimate(ctx context.Context, info PoolInfo) (PoolRewardEstimate, bool) {
+	now := a.now()
+	samples, err := a.store.GetPoolSamples(ctx, info.PoolID, now.Add(-poolStatsWindow))
+	if err != nil {
+		log.Printf("Error getting pool samples: %v", err)
+		return PoolRewardEstimate{}, false
+	}
+	samples = append(samples, PoolSample{
+		PoolID:             info.PoolID,
+		ObservedAt:         now,
+		StakerBalance:      info.StakerBalance,
+		DelegationsBalance: info.DelegationsBalance,
+	})
+	return estimatePoolRewards(samples, info, a.blockRewardFor(ctx, info.PoolID))
+}
+
+// blockRewardFor is the reward of the latest block on the pool's network.
+func (a *App) blockRewardFor(ctx context.Context, poolID string) Amount {
+	if tip := a.tipFor(ctx, poolID); tip != nil {
+		if latest, ok := tip.LatestBlock(); ok && latest.Reward.Sign() > 0 {
+			return latest.Reward
+		}
+	}
+	return defaultBlockReward
+}
+
+// formatEstimateML renders an estimated amount with at most two decimals.
+func (a *App) formatEstimateML(amount Amount) string {
+	return amount.FormatML(min(a.amountDecimals, 2)) + " ML"
+}
+
+// recordPoolSample stores the pool's balances for reward estimates, at most
+// once per poolSampleInterval.
+func (a *App) recordPoolSample(ctx context.Context, info PoolInfo, height int64) {
+	sample := PoolSample{
+		PoolID:             info.PoolID,
+		ObservedAt:         a.now(),
+		Height:             height,
+		StakerBalance:      info.StakerBalance,
+		DelegationsBalance: info.DelegationsBalance,
+	}
+	if err := a.store.AddPoolSample(ctx, sample, poolSampleInterval); err != nil {
+		log.Printf("Error storing pool sample: %v", err)
+	}
+}
+
+// samplePoolsRoutine records a balance sample of every tracked pool once
+// per poolSampleInterval until ctx is cancelled, whether or not its users
+// have notifications on.
+func (a *App) samplePoolsRoutine(ctx context.Context) {
+	ctx = withBackgroundPriority(ctx)
+	ticker := time.NewTicker(poolSampleInterval)
+	defer ticker.Stop()
+	for {
+		a.samplePools(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) samplePools(ctx context.Context) {
+	if a.apiCircuitOpen() {
+		return
+	}
+	pools, err := a.store.GetTrackedPools(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error getting tracked pools: %v", err)
+		}
+		return
+	}
+	runTasksWithLimit(pools, 10, func(poolID string) {
+		info, err := a.client.GetPoolInfo(ctx, poolID)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Error sampling pool %s: %v", poolID, err)
+			}
+			return
+		}
+		if info.State != PoolStateActive {
+			return
+		}
+		var height int64
+		if tip := a.tipFor(ctx, poolID); tip != nil {
+			height, _ = tip.Tip()
+		}
+		a.recordPoolSample(ctx, info, height)
+	})
+}
+
+// prunePoolSamplesRoutine drops samples older than poolSampleRetention once
+// a day until ctx is cancelled.
+func (a *App) prunePoolSamplesRoutine(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		if err := a.store.PrunePoolSamples(ctx, time.Now().Add(-poolSampleRetention)); err != nil && ctx.Err() == nil {
+			log.Printf("Error pruning pool samples: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) poolBlocksHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := update.Message.From.ID
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 3 || (parts[2] != "on" && parts[2] != "off") {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Usage: `/pool_blocks <poolID> on|off`")
+		return
+	}
+
+	parsed, err := a.networks.ParsePoolID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid pool ID: "+err.Error())
+		return
+	}
+	poolID := parsed.String()
+	enabled := parts[2] == "on"
+
+	// Only blocks produced from now on are announced.
+	var fromHeight int64
+	if tip := a.tipFor(ctx, poolID); tip != nil {
+		fromHeight, _ = tip.Tip()
+	}
+	err = a.store.SetPoolBlockNotifications(ctx, fmt.Sprint(userID), poolID, enabled, fromHeight)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "You are not tracking this pool. Add it with `/pool_add <poolID>` first.")
+	case err != nil:
+		log.Printf("Error updating block notifications: %v", err)
+		a.sendCommandError(ctx, b, update.Message.Chat.ID)
+	case enabled:
+		a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("Block notifications enabled for `%s`", poolID))
+	default:
+		a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("Block notifications disabled for `%s`", poolID))
+	}
+}
+
+func (a *App) poolLivenessHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := update.Message.From.ID
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 3 {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Usage: `/pool_liveness <poolID> on|<hours>|off`")
+		return
+	}
+
+	parsed, err := a.networks.ParsePoolID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid pool ID: "+err.Error())
+		return
+	}
+	poolID := parsed.String()
+	enabled := parts[2] != "off"
+	var window time.Duration
+	if enabled && parts[2] != "on" {
+		hours, err := strconv.Atoi(parts[2])
+		if err != nil || hours < 1 || hours > 720 {
+			a.sendMessage(ctx, b, update.Message.Chat.ID, "The window must be `on`, a number of hours between 1 and 720, or `off`")
+			return
+		}
+		window = time.Duration(hours) * time.Hour
+	}
+
+	// The watch starts now, measured on the chain's clock when it is known.
+	since := BlockInfo{Timestamp: time.Now()}
+	if tip := a.tipFor(ctx, poolID); tip != nil {
+		if latest, ok := tip.LatestBlock(); ok {
+			since = latest
+		}
+	}
+	err = a.store.SetPoolLiveness(ctx, fmt.Sprint(userID), poolID, enabled, window, since)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "You are not tracking this pool. Add it with `/pool_add <poolID>` first.")
+	case err != nil:
+		log.Printf("Error updating pool liveness: %v", err)
+		a.sendCommandError(ctx, b, update.Message.Chat.ID)
+	case !enabled:
+		a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("Liveness alerts disabled for `%s`", poolID))
+	case window == 0:
+		a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("You will be alerted when `%s` goes without a block for much longer than its stake share makes likely", poolID))
+	default:
+		a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("You will be alerted when `%s` produces no block for %s", poolID, formatGap(window)))
+	}
+}
+
+func (a *App) poolDelegationsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := update.Message.From.ID
+	parts := strings.Fields(update.Message.Text)
+	usage := "Usage: `/pool_delegations <poolID> [notify <ML>|off]`"
+	if len(parts) < 2 || (len(parts) > 2 && (parts[2] != "notify" || len(parts) < 4)) {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, usage)
+		return
+	}
+
+	parsed, err := a.networks.ParsePoolID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid pool ID: "+err.Error())
+		return
+	}
+	poolID := parsed.String()
+	enabled, threshold := false, Amount{}
+	if len(parts) > 2 && parts[3] != "off" {
+		ml, err := strconv.Atoi(parts[3])
+		if err != nil || ml < 0 {
+			a.sendMessage(ctx, b, update.Message.Chat.ID, "The withdrawal threshold must be a whole number of ML, or `off`")
+			return
+		}
+		enabled, threshold = true, AmountFromML(int64(ml))
+	}
+
+	delegations, err := a.client.GetPoolDelegations(ctx, poolID)
+	if err != nil {
+		log.Printf("Error getting delegations of pool %s: %v", poolID, err)
+		a.sendAPIError(ctx, b, update.Message.Chat.ID, err)
+		return
+	}
+
+	if len(parts) > 2 {
+		err := a.store.SetPoolDelegationAlerts(ctx, fmt.Sprint(userID), poolID, enabled, threshold, delegationBalances(delegations))
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			a.sendMessage(ctx, b, update.Message.Chat.ID, "You are not tracking this pool. Add it with `/pool_add <poolID>` first.")
+		case err != nil:
+			log.Printf("Error updating delegation alerts: %v", err)
+			a.sendCommandError(ctx, b, update.Message.Chat.ID)
+		case enabled:
+			a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("You will be notified of new delegations to `%s` and withdrawals of at least %v", poolID, a.formatML(threshold)))
+		default:
+			a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("Delegation alerts disabled for `%s`", poolID))
+		}
+		return
+	}
+
+	funded := fundedDelegations(delegations)
+	sortDelegationsByBalance(funded)
+	var total Amount
+	for _, delegation := range funded {
+		total = total.Add(delegation.Balance)
+	}
+	msg := fmt.Sprintf("Pool `%s`: `%d` delegations, total `%v`\n", poolID, len(funded), a.formatML(total))
+	if len(funded) > 0 {
+		msg += "Top delegators:\n"
+	}
+	for i, delegation := range funded {
+		if i == topDelegatorsShown {
+			msg += fmt.Sprintf("and %d more\n", len(funded)-topDelegatorsShown)
+			break
+		}
+		msg += fmt.Sprintf("`%s`: %v\n", delegation.DelegationID, a.formatML(delegation.Balance))
+	}
+	a.sendLongMessage(ctx, b, update.Message.Chat.ID, msg)
+}
+
+func (a *App) formatPoolInfo(info PoolInfo) string {
+	msg := fmt.Sprintf("Pool `%s`\n", info.PoolID)
+	msg += fmt.Sprintf("State: `%v`\n", info.State)
+	if info.State == PoolStateNotFound {
+		return msg
+	}
+	msg += fmt.Sprintf("Pledge: `%v`\n", a.formatML(info.StakerBalance))
+	msg += fmt.Sprintf("Delegations balance: `%v`\n", a.formatML(info.DelegationsBalance))
+	msg += fmt.Sprintf("Total stake: `%v`\n", a.formatML(info.TotalStake()))
+	msg += fmt.Sprintf("Margin ratio: `%v`\n", formatPerThousand(info.MarginRatioPerThousand))
+	msg += fmt.Sprintf("Cost per block: `%v`\n", a.formatML(info.CostPerBlock))
+	if info.VRFPublicKey != "" {
+		msg += fmt.Sprintf("VRF public key: `%s`\n", info.VRFPublicKey)
+	}
+	if info.StakerDestination != "" {
+		msg += fmt.Sprintf("Staker key: `%s`\n", info.StakerDestination)
+	}
+	if info.DecommissionDestination != "" {
+		msg += fmt.Sprintf("Decommission key: `%s`\n", info.DecommissionDestination)
+	}
+	return msg
+}
+
 func (a *App) addDelegationHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	userID := update.Message.From.ID
 	parts := strings.Fields(update.Message.Text)
@@ -188,13 +959,13 @@ func (a *App) addDelegationHandler(ctx context.Context, b *bot.Bot, update *mode
 		return
 	}
 
-	delegationID := parts[1]
-	if !validateBech32Address(delegationID) {
-		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid delegation ID")
+	delegationID, err := a.networks.ParseDelegationID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid delegation ID: "+err.Error())
 		return
 	}
 
-	err := a.store.AddDelegation(ctx, fmt.Sprint(userID), delegationID)
+	err = a.store.AddDelegation(ctx, fmt.Sprint(userID), delegationID.String(), delegationID.Network.Name)
 	if err != nil {
 		log.Printf("Error adding delegation: %v", err)
 		a.sendCommandError(ctx, b, update.Message.Chat.ID)
@@ -211,14 +982,13 @@ func (a *App) removeDelegationHandler(ctx context.Context, b *bot.Bot, update *m
 		return
 	}
 
-	delegationID := parts[1]
-
-	if !validateBech32Address(delegationID) {
-		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid delegation ID")
+	delegationID, err := a.networks.ParseDelegationID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid delegation ID: "+err.Error())
 		return
 	}
 
-	err := a.store.RemoveDelegation(ctx, fmt.Sprint(userID), delegationID)
+	err = a.store.RemoveDelegation(ctx, fmt.Sprint(userID), delegationID.String())
 	if err != nil {
 		log.Printf("Error removing delegation: %v", err)
 		a.sendCommandError(ctx, b, update.Message.Chat.ID)
@@ -229,7 +999,6 @@ func (a *App) removeDelegationHandler(ctx context.Context, b *bot.Bot, update *m
 
 func (a *App) listDelegationsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	userID := update.Message.From.ID
-	p := message.NewPrinter(language.AmericanEnglish)
 
 	delegations, err := a.store.GetDelegations(ctx, fmt.Sprint(userID))
 	if err != nil {
@@ -239,24 +1008,125 @@ func (a *App) listDelegationsHandler(ctx context.Context, b *bot.Bot, update *mo
 		if len(delegations) == 0 {
 			a.sendMessage(ctx, b, update.Message.Chat.ID, "You have no delegations")
 		} else {
-			balances, err := runFetchMapWithLimit(delegations, 10, func(delegationID string) (int64, error) {
-				return a.client.GetDelegationBalance(delegationID)
+			results := fetchAll(delegations, func(delegationID string) (Amount, error) {
+				return a.client.GetDelegationBalance(ctx, delegationID)
 			})
-			if err != nil {
+			if err := blockingLookupError(results); err != nil {
 				log.Printf("Error getting delegation balance: %v", err)
-				a.sendCommandError(ctx, b, update.Message.Chat.ID)
+				a.sendAPIError(ctx, b, update.Message.Chat.ID, err)
 				return
 			}
+			rate := a.fiatRateFor(ctx, fmt.Sprint(userID))
 			delegationMessage := "Your delegations:\n"
 			for _, delegationID := range delegations {
-				balance := balances[delegationID]
-				delegationMessage += p.Sprintf("`%v`: %v ML \n", delegationID, balance)
+				result := results[delegationID]
+				if result.err != nil {
+					log.Printf("Error getting balance of delegation %s: %v", delegationID, result.err)
+					delegationMessage += fmt.Sprintf("`%v`%s: `%v` \n", delegationID, a.networkLabel(ctx, delegationID), lookupErrorLabel(result.err))
+				} else {
+					delegationMessage += fmt.Sprintf("`%v`%s: %v%v \n", delegationID, a.networkLabel(ctx, delegationID), a.formatML(result.value), rate.suffix(result.value))
+				}
 			}
 			a.sendLongMessage(ctx, b, update.Message.Chat.ID, delegationMessage)
 		}
 	}
 }
 
+func (a *App) delegationInfoHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Usage: `/delegation_info <delegationID>`")
+		return
+	}
+
+	parsed, err := a.networks.ParseDelegationID(parts[1])
+	if err != nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid delegation ID: "+err.Error())
+		return
+	}
+	delegationID := parsed.String()
+
+	info, err := a.client.GetDelegationInfo(ctx, delegationID)
+	if err != nil {
+		log.Printf("Error getting delegation info: %v", err)
+		a.sendAPIError(ctx, b, update.Message.Chat.ID, err)
+		return
+	}
+
+	poolState := "unknown"
+	if info.PoolID != "" {
+		status, err := a.client.GetPoolStatus(ctx, info.PoolID)
+		if err != nil {
+			log.Printf("Error getting status of pool %s: %v", info.PoolID, err)
+			poolState = lookupErrorLabel(err)
+		} else {
+			poolState = status.State.String()
+		}
+	}
+
+	msg := fmt.Sprintf("Delegation `%s`\n", info.DelegationID)
+	msg += fmt.Sprintf("Pool: `%s`\n", info.PoolID)
+	msg += fmt.Sprintf("Pool state: `%s`\n", poolState)
+	msg += fmt.Sprintf("Balance: `%v`\n", a.formatML(info.Balance))
+	msg += fmt.Sprintf("Spend destination: `%s`\n", info.SpendDestination)
+	msg += fmt.Sprintf("Created at block: `%d`\n", info.CreationBlockHeight)
+	a.sendLongMessage(ctx, b, update.Message.Chat.ID, msg)
+}
+
+func (a *App) currencyHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if a.prices == nil {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Fiat values are not enabled on this bot.")
+		return
+	}
+	userID := fmt.Sprint(update.Message.From.ID)
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		currency, err := a.store.GetUserCurrency(ctx, userID)
+		if err != nil {
+			log.Printf("Error getting currency: %v", err)
+			a.sendCommandError(ctx, b, update.Message.Chat.ID)
+			return
+		}
+		if currency == "" {
+			a.sendMessage(ctx, b, update.Message.Chat.ID, "Fiat values are off. Usage: `/currency <code>|off`, e.g. `/currency USD`")
+		} else {
+			a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("ML amounts are also shown in `%s`. Use `/currency off` to stop.", currency))
+		}
+		return
+	}
+
+	if strings.EqualFold(parts[1], "off") {
+		if err := a.store.SetUserCurrency(ctx, userID, ""); err != nil {
+			log.Printf("Error setting currency: %v", err)
+			a.sendCommandError(ctx, b, update.Message.Chat.ID)
+			return
+		}
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Fiat values turned off")
+		return
+	}
+	currency, ok := parseCurrency(parts[1])
+	if !ok {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "Invalid currency, use a three letter code like `USD` or `EUR`")
+		return
+	}
+	price, err := a.prices.Price(ctx, currency)
+	if errors.Is(err, ErrUnknownCurrency) {
+		a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("No ML price is available in `%s`", currency))
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting the ML price in %s: %v", currency, err)
+		a.sendMessage(ctx, b, update.Message.Chat.ID, "The price source is unavailable. Please try again later.")
+		return
+	}
+	if err := a.store.SetUserCurrency(ctx, userID, currency); err != nil {
+		log.Printf("Error setting currency: %v", err)
+		a.sendCommandError(ctx, b, update.Message.Chat.ID)
+		return
+	}
+	a.sendMessage(ctx, b, update.Message.Chat.ID, fmt.Sprintf("ML amounts are now also shown in `%s`, 1 ML ≈ %g %s", currency, price, currency))
+}
+
 func (a *App) balanceHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	userID := update.Message.From.ID
 
@@ -267,8 +1137,9 @@ func (a *App) balanceHandler(ctx context.Context, b *bot.Bot, update *models.Upd
 		return
 	}
 
-	var poolsTotalBalance int64
-	var delegationsTotalBalance int64
+	var poolsTotalBalance Amount
+	var delegationsTotalBalance Amount
+	var addressesTotalBalance Amount
 
 	delegations, err := a.store.GetDelegations(ctx, fmt.Sprint(userID))
 	if err != nil {
@@ -277,43 +1148,126 @@ func (a *App) balanceHandler(ctx context.Context, b *bot.Bot, update *models.Upd
 		return
 	}
 
-	errCh := make(chan error, 2)
+	addresses, err := a.store.GetMonitoredAddresses(ctx, fmt.Sprint(userID))
+	if err != nil {
+		log.Printf("Error getting addresses: %v", err)
+		a.sendCommandError(ctx, b, update.Message.Chat.ID)
+		return
+	}
+	addressIDs := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		addressIDs = append(addressIDs, address.Address)
+	}
+
+	// IDs unknown to the API server and decommissioned pools count as zero
+	// but are reported to the user; any other failure aborts the command.
+	var notFound int32
+	var decommissioned int
+	skipNotFound := func(balance Amount, err error) (Amount, error) {
+		if errors.Is(err, ErrNotFound) {
+			atomic.AddInt32(&notFound, 1)
+			return Amount{}, nil
+		}
+		return balance, err
+	}
+
+	errCh := make(chan error, 3)
 
 	go func() {
-		poolErr := runWithLimit(pools, 10, func(poolID string) (int64, error) {
-			return a.client.GetPoolBalance(poolID)
-		}, func(balance int64) {
-			poolsTotalBalance += balance
+		poolErr := runWithLimit(pools, 10, func(poolID string) (PoolStatus, error) {
+			return a.client.GetPoolStatus(ctx, poolID)
+		}, func(status PoolStatus) {
+			switch status.State {
+			case PoolStateNotFound:
+				atomic.AddInt32(&notFound, 1)
+			case PoolStateDecommissioned:
+				decommissioned++
+			default:
+				poolsTotalBalance = poolsTotalBalance.Add(status.Balance)
+			}
 		})
 		errCh <- poolErr
 	}()
 
 	go func() {
-		delegationErr := runWithLimit(delegations, 10, func(delegationID string) (int64, error) {
-			return a.client.GetDelegationBalance(delegationID)
-		}, func(balance int64) {
-			delegationsTotalBalance += balance
+		delegationErr := runWithLimit(delegations, 10, func(delegationID string) (Amount, error) {
+			return skipNotFound(a.client.GetDelegationBalance(ctx, delegationID))
+		}, func(balance Amount) {
+			delegationsTotalBalance = delegationsTotalBalance.Add(balance)
 		})
 		errCh <- delegationErr
 	}()
 
-	for i := 0; i < 2; i++ {
+	go func() {
+		addressErr := runWithLimit(addressIDs, 10, func(address string) (AddressBalance, error) {
+			return a.client.GetAddressBalance(ctx, address)
+		}, func(balance AddressBalance) {
+			addressesTotalBalance = addressesTotalBalance.Add(balance.Coins)
+		})
+		errCh <- addressErr
+	}()
+
+	for i := 0; i < 3; i++ {
 		if err := <-errCh; err != nil {
 			log.Printf("Error getting balance: %v", err)
-			a.sendCommandError(ctx, b, update.Message.Chat.ID)
+			a.sendAPIError(ctx, b, update.Message.Chat.ID, err)
 			return
 		}
 	}
 
-	p := message.NewPrinter(language.AmericanEnglish)
-	msg := p.Sprintf("`%v` pools: `%v ML`\n", len(pools), poolsTotalBalance)
-	msg += p.Sprintf("`%v` delegations: `%v ML`\n", len(delegations), delegationsTotalBalance)
-	msg += p.Sprintf("Total: `%v ML`", poolsTotalBalance+delegationsTotalBalance)
-
-	a.sendMessage(ctx, b, update.Message.Chat.ID, msg)
+	rate := a.fiatRateFor(ctx, fmt.Sprint(userID))
+	total := poolsTotalBalance.Add(delegationsTotalBalance).Add(addressesTotalBalance)
+	p := message.NewPrinter(language.AmericanEnglish)
+	msg := p.Sprintf("`%v` pools: `%v`%v\n", len(pools), a.formatML(poolsTotalBalance), rate.suffix(poolsTotalBalance))
+	if decommissioned > 0 {
+		msg += p.Sprintf("`%v` decommissioned pools\n", decommissioned)
+	}
+	msg += p.Sprintf("`%v` delegations: `%v`%v\n", len(delegations), a.formatML(delegationsTotalBalance), rate.suffix(delegationsTotalBalance))
+	if len(addresses) > 0 {
+		msg += p.Sprintf("`%v` addresses: `%v`%v\n", len(addresses), a.formatML(addressesTotalBalance), rate.suffix(addressesTotalBalance))
+	}
+	if missing := atomic.LoadInt32(&notFound); missing > 0 {
+		msg += p.Sprintf("`%v` IDs not found on chain\n", missing)
+	}
+	msg += p.Sprintf("Total: `%v`%v", a.formatML(total), rate.suffix(total))
+
+	a.sendMessage(ctx, b, update.Message.Chat.ID, msg)
+}
+
+// lookupResult carries a per-ID lookup outcome so list commands can show
+// failures next to the ID instead of aborting the whole reply.
+type lookupResult[T any] struct {
+	value T
+	err   error
+}
+
+func fetchAll[T any](ids []string, fetch func(id string) (T, error)) map[string]lookupResult[T] {
+	results, _ := runFetchMapWithLimit(ids, 10, func(id string) (lookupResult[T], error) {
+		value, err := fetch(id)
+		return lookupResult[T]{value: value, err: err}, nil
+	})
+	return results
+}
+
+// blockingLookupError returns the first failure that makes a per-ID listing
+// pointless: rate limiting or a cancelled request.
+func blockingLookupError[T any](results map[string]lookupResult[T]) error {
+	for _, result := range results {
+		if errors.Is(result.err, ErrRateLimited) || errors.Is(result.err, context.Canceled) {
+			return result.err
+		}
+	}
+	return nil
+}
+
+func lookupErrorLabel(err error) string {
+	if errors.Is(err, ErrNotFound) {
+		return "not found"
+	}
+	return "unavailable"
 }
 
-func runWithLimit(ids []string, limit int, fetch func(id string) (int64, error), add func(balance int64)) error {
+func runWithLimit[T any](ids []string, limit int, fetch func(id string) (T, error), add func(result T)) error {
 	if len(ids) == 0 {
 		return nil
 	}
@@ -331,7 +1285,7 @@ func runWithLimit(ids []string, limit int, fetch func(id string) (int64, error),
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			balance, err := fetch(id)
+			result, err := fetch(id)
 			mu.Lock()
 			defer mu.Unlock()
 			if err != nil {
@@ -340,7 +1294,7 @@ func runWithLimit(ids []string, limit int, fetch func(id string) (int64, error),
 				}
 				return
 			}
-			add(balance)
+			add(result)
 		}(id)
 	}
 
@@ -368,15 +1322,15 @@ func runTasksWithLimit(ids []string, limit int, task func(id string)) {
 	wg.Wait()
 }
 
-func runFetchMapWithLimit(ids []string, limit int, fetch func(id string) (int64, error)) (map[string]int64, error) {
+func runFetchMapWithLimit[T any](ids []string, limit int, fetch func(id string) (T, error)) (map[string]T, error) {
 	if len(ids) == 0 {
-		return map[string]int64{}, nil
+		return map[string]T{}, nil
 	}
 	var (
 		wg       sync.WaitGroup
 		mu       sync.Mutex
 		firstErr error
-		results  = make(map[string]int64, len(ids))
+		results  = make(map[string]T, len(ids))
 	)
 	sem := make(chan struct{}, limit)
 
@@ -387,7 +1341,7 @@ func runFetchMapWithLimit(ids []string, limit int, fetch func(id string) (int64,
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			balance, err := fetch(id)
+			result, err := fetch(id)
 			mu.Lock()
 			defer mu.Unlock()
 			if err != nil {
@@ -396,7 +1350,7 @@ func runFetchMapWithLimit(ids []string, limit int, fetch func(id string) (int64,
 				}
 				return
 			}
-			results[id] = balance
+			results[id] = result
 		}(id)
 	}
 
@@ -594,6 +1548,63 @@ func (a *App) debugStatusHandler(ctx context.Context, b *bot.Bot, update *models
 	a.sendMessage(ctx, b, chatID, msg)
 }
 
+func (a *App) debugAPIHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userID := fmt.Sprint(update.Message.From.ID)
+	chatID := update.Message.Chat.ID
+
+	if a.adminUser == "" || userID != a.adminUser {
+		a.sendMessage(ctx, b, chatID, "Unauthorized")
+		return
+	}
+
+	reporter, ok := a.client.(endpointStatusReporter)
+	if !ok {
+		a.sendMessage(ctx, b, chatID, "No API server diagnostics available")
+		return
+	}
+	msg := a.formatEndpointStatuses(reporter.EndpointStatuses())
+	if cache, ok := a.client.(cacheStatsReporter); ok {
+		stats := cache.CacheStats()
+		msg += fmt.Sprintf("Cache: %d hits, %d coalesced, %d misses\n", stats.Hits, stats.Coalesced, stats.Misses)
+	}
+	a.sendLongMessage(ctx, b, chatID, msg)
+}
+
+func (a *App) formatEndpointStatuses(statuses []EndpointStatus) string {
+	var sb strings.Builder
+	sb.WriteString("API servers:\n")
+	for _, status := range statuses {
+		state := "healthy"
+		if !status.Healthy {
+			state = "unhealthy"
+		}
+		fmt.Fprintf(&sb, "%s `%s`: %s", status.Network, status.BaseURL, state)
+		if status.Active {
+			sb.WriteString(", in use")
+		}
+		if status.LastError != "" {
+			fmt.Fprintf(&sb, " (`%s`)", strings.ReplaceAll(status.LastError, "`", "'"))
+		}
+		sb.WriteString("\n")
+	}
+	for i, status := range statuses {
+		if status.Circuit != "" && status.Circuit != breakerClosed.String() && (i == 0 || statuses[i-1].Network != status.Network) {
+			fmt.Fprintf(&sb, "%s circuit breaker: `%s`\n", status.Network, status.Circuit)
+		}
+	}
+	networks := make([]string, 0, len(a.networkTips))
+	for network := range a.networkTips {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	for _, network := range networks {
+		if height, _ := a.networkTips[network].Tip(); height > 0 {
+			fmt.Fprintf(&sb, "%s tip: `%d`\n", network, height)
+		}
+	}
+	return sb.String()
+}
+
 func (a *App) debugStopHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	userID := fmt.Sprint(update.Message.From.ID)
 	chatID := update.Message.Chat.ID
@@ -706,9 +1717,13 @@ func (a *App) notifyStopHanlder(ctx context.Context, b *bot.Bot, update *models.
 	}
 }
 
+// notifyBalanceChangesRoutine re-checks the user's balances whenever the
+// chain tip advances, and at least every maxCheckInterval in case the tip
+// cannot be fetched.
 func (a *App) notifyBalanceChangesRoutine(ctx context.Context, userID string, chatID int64) {
-	ticker := time.NewTicker(10 * time.Minute)
-	defer ticker.Stop()
+	ctx = withBackgroundPriority(ctx)
+	fallback := time.NewTimer(a.maxCheckInterval)
+	defer fallback.Stop()
 	for {
 		select {
 		case <-ctx.Done():
@@ -716,48 +1731,395 @@ func (a *App) notifyBalanceChangesRoutine(ctx context.Context, userID string, ch
 			return
 		default:
 		}
-		a.notifyPoolsBalanceChanges(ctx, userID, chatID)
-		a.notifyDelegationsBalanceChanges(ctx, userID, chatID)
+		var height int64
+		var newBlock <-chan struct{}
+		cycleCtx := ctx
+		if a.tip != nil {
+			height, newBlock = a.tip.Tip()
+			// Balances cached before the new block was seen may predate it;
+			// ones fetched since, e.g. for another user, are fresh enough.
+			cycleCtx = withFreshAfter(ctx, a.tip.SeenAt())
+		}
+		if a.apiCircuitOpen() {
+			log.Printf("API circuit breaker open, skipping this cycle for user %s", userID)
+		} else {
+			a.syncAddressDelegations(cycleCtx, userID, chatID)
+			a.notifyPoolsBalanceChanges(cycleCtx, userID, chatID, height)
+			a.notifyDelegationsBalanceChanges(cycleCtx, userID, chatID, height)
+			a.notifyAddressesBalanceChanges(cycleCtx, userID, chatID, height)
+			a.notifyAddressTransfers(cycleCtx, userID, chatID)
+			a.notifyPoolDelegations(cycleCtx, userID, chatID, height)
+		}
+		a.notifyPoolBlocks(ctx, userID, chatID)
+		a.notifyPoolLiveness(ctx, userID, chatID)
 
+		if !fallback.Stop() {
+			select {
+			case <-fallback.C:
+			default:
+			}
+		}
+		fallback.Reset(a.maxCheckInterval)
 		select {
 		case <-ctx.Done():
 			log.Println("Stopping notification for user ", userID)
 			return
-		case <-ticker.C:
+		case <-newBlock:
+		case <-fallback.C:
+		}
+	}
+}
+
+// syncAddressDelegations starts tracking delegations that appeared for the
+// addresses the user imported with syncing on. Their balance is announced by
+// the delegation check that follows.
+func (a *App) syncAddressDelegations(ctx context.Context, userID string, chatID int64) {
+	addresses, err := a.store.GetSyncedAddresses(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting synced addresses: %v", err)
+		return
+	}
+	if len(addresses) == 0 {
+		return
+	}
+	tracked, err := a.store.GetDelegations(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting delegations: %v", err)
+		return
+	}
+
+	cycleCtx, stopCycle := context.WithCancel(ctx)
+	defer stopCycle()
+	for _, address := range addresses {
+		if cycleCtx.Err() != nil {
+			return
+		}
+		parsed, err := a.networks.ParseAddress(address)
+		if err != nil {
+			log.Printf("Skipping synced address %s: %v", address, err)
+			continue
+		}
+		delegations, err := a.client.GetAddressDelegations(cycleCtx, address)
+		if err != nil {
+			logNotificationLookupError("address delegations", address, err, stopCycle)
+			continue
+		}
+		var added []string
+		for _, delegation := range fundedDelegations(delegations) {
+			if !slices.Contains(tracked, delegation.DelegationID) {
+				added = append(added, delegation.DelegationID)
+			}
+		}
+		if len(added) == 0 {
+			continue
+		}
+		if err := a.store.ImportPoolsAndDelegations(ctx, userID, parsed.Network.Name, nil, added); err != nil {
+			log.Printf("Error adding synced delegations: %v", err)
+			continue
+		}
+		tracked = append(tracked, added...)
+		for _, delegationID := range added {
+			a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: now tracking new delegation `%s`", address, delegationID))
+		}
+	}
+}
+
+// notifyPoolBlocks announces blocks produced by pools the user enabled block
+// notifications for. The last announced height is stored before sending so a
+// restart never repeats a block.
+func (a *App) notifyPoolBlocks(ctx context.Context, userID string, chatID int64) {
+	pools, err := a.store.GetBlockNotificationPools(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting block notification pools: %v", err)
+		return
+	}
+	poolIDs := make([]string, 0, len(pools))
+	for poolID := range pools {
+		poolIDs = append(poolIDs, poolID)
+	}
+	sort.Strings(poolIDs)
+
+	// Blocks are fetched once per network, from the oldest one a pool has
+	// not seen yet.
+	since := make(map[*ChainTipWatcher]int64)
+	for _, poolID := range poolIDs {
+		tip := a.tipFor(ctx, poolID)
+		if tip == nil {
+			continue
+		}
+		if last, ok := since[tip]; !ok || pools[poolID] < last {
+			since[tip] = pools[poolID]
+		}
+	}
+	blocks := make(map[*ChainTipWatcher][]BlockInfo, len(since))
+	for tip, height := range since {
+		var err error
+		blocks[tip], err = tip.BlocksSince(ctx, height)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error catching up on blocks after %d: %v", height, err)
+		}
+	}
+
+	for _, poolID := range poolIDs {
+		tip := a.tipFor(ctx, poolID)
+		if tip == nil {
+			continue
+		}
+		stored, scanned := pools[poolID], pools[poolID]
+		for _, block := range blocks[tip] {
+			if block.Height <= scanned {
+				continue
+			}
+			scanned = block.Height
+			if block.PoolID != poolID {
+				continue
+			}
+			if err := a.store.UpdatePoolLastBlock(ctx, userID, poolID, block.Height); err != nil {
+				log.Printf("Error updating last block: %v", err)
+				scanned = stored
+				break
+			}
+			stored = block.Height
+			msg := fmt.Sprintf("`%s`: produced block `%d` at %s", poolID, block.Height, block.Timestamp.Format("2006-01-02 15:04:05 UTC"))
+			if block.Reward.Sign() > 0 {
+				msg += fmt.Sprintf(", reward %v", a.formatML(block.Reward))
+			}
+			a.sendMessage(ctx, a.bot, chatID, msg)
+		}
+		// Blocks of other pools are not fetched again either.
+		if scanned > stored {
+			if err := a.store.UpdatePoolLastBlock(ctx, userID, poolID, scanned); err != nil {
+				log.Printf("Error updating last block: %v", err)
+			}
+		}
+	}
+}
+
+// notifyPoolLiveness alerts once when a watched pool has produced no block
+// for longer than its window, or than is likely at its stake share, and
+// sends a recovery message when it produces one again. Blocks are scanned
+// from the stored height, so a restart catches up on the blocks it missed.
+// Gaps are measured against the newest known block rather than the wall
+// clock, so an unreachable API server never looks like a stalled pool.
+func (a *App) notifyPoolLiveness(ctx context.Context, userID string, chatID int64) {
+	pools, err := a.store.GetPoolLiveness(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting pool liveness: %v", err)
+		return
+	}
+
+	// Blocks are fetched once per network, from the oldest height a pool has
+	// not been scanned at.
+	since := make(map[*ChainTipWatcher]int64)
+	for _, pool := range pools {
+		tip := a.tipFor(ctx, pool.PoolID)
+		if tip == nil {
+			continue
+		}
+		// Pools never scanned yet start from the blocks the watcher holds.
+		height := pool.Height
+		if last, ok := since[tip]; ok && (height <= 0 || (last > 0 && last < height)) {
+			height = last
+		}
+		since[tip] = height
+	}
+	blocks := make(map[*ChainTipWatcher][]BlockInfo, len(since))
+	for tip, height := range since {
+		var err error
+		blocks[tip], err = tip.BlocksSince(ctx, height)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Error catching up on blocks after %d: %v", height, err)
+			}
+			// Without every block a gap could be one that was not fetched.
+			delete(blocks, tip)
+		}
+	}
+
+	for _, pool := range pools {
+		tip := a.tipFor(ctx, pool.PoolID)
+		scanned, ok := blocks[tip]
+		if !ok {
+			continue
+		}
+		latest, ok := tip.LatestBlock()
+		if !ok {
+			continue
+		}
+		var poolBlocks []BlockInfo
+		for _, block := range scanned {
+			if block.Height > pool.Height {
+				poolBlocks = append(poolBlocks, block)
+			}
+		}
+		height, lastBlockTime := pool.Height, pool.LastBlockTime
+		if len(poolBlocks) > 0 {
+			height = poolBlocks[len(poolBlocks)-1].Height
+			if pool.Height > 0 && poolBlocks[0].Height > pool.Height+1 && poolBlocks[0].Timestamp.After(lastBlockTime) {
+				// Blocks too far back to fetch may hold one of the pool's, so
+				// the watch restarts after them.
+				lastBlockTime = poolBlocks[0].Timestamp
+			}
+		}
+		if block, produced := pool.latestPoolBlock(poolBlocks); produced {
+			if err := a.store.UpdatePoolLiveness(ctx, userID, pool.PoolID, height, block.Timestamp, false); err != nil {
+				log.Printf("Error updating pool liveness: %v", err)
+				continue
+			}
+			if pool.Alerted {
+				a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: producing blocks again at block `%d` after %s without blocks",
+					pool.PoolID, block.Height, formatGap(block.Timestamp.Sub(pool.LastBlockTime))))
+			}
+			continue
+		}
+
+		gap := latest.Timestamp.Sub(lastBlockTime)
+		alert := ""
+		switch {
+		case pool.Alerted:
+		case pool.Window > 0:
+			if gap > pool.Window {
+				alert = fmt.Sprintf("`%s`: no block produced for %s (alert window %s)", pool.PoolID, formatGap(gap), formatGap(pool.Window))
+			}
+		case gap > minLivenessWindow:
+			if interval, ok := a.expectedPoolBlockInterval(ctx, pool.PoolID); ok && gap > livenessWindowFor(interval) {
+				alert = fmt.Sprintf("`%s`: no block produced for %s, one is expected every %s at its stake share", pool.PoolID, formatGap(gap), formatGap(interval))
+			}
+		}
+		if height == pool.Height && lastBlockTime.Equal(pool.LastBlockTime) && alert == "" {
+			continue
+		}
+		if err := a.store.UpdatePoolLiveness(ctx, userID, pool.PoolID, height, lastBlockTime, pool.Alerted || alert != ""); err != nil {
+			log.Printf("Error updating pool liveness: %v", err)
+			continue
+		}
+		if alert != "" {
+			a.sendMessage(ctx, a.bot, chatID, alert)
+		}
+	}
+}
+
+// expectedPoolBlockInterval is the mean time between the pool's blocks given
+// its share of the network's stake. It reports false when the share is not
+// known or the pool holds none.
+func (a *App) expectedPoolBlockInterval(ctx context.Context, poolID string) (time.Duration, bool) {
+	info, err := a.client.GetPoolInfo(ctx, poolID)
+	if err != nil {
+		log.Printf("Error getting pool info of %s: %v", poolID, err)
+		return 0, false
+	}
+	total, err := a.client.GetTotalStake(ctx, poolID)
+	if err != nil {
+		log.Printf("Error getting the total stake: %v", err)
+		return 0, false
+	}
+	if info.State != PoolStateActive || total.Sign() <= 0 || info.TotalStake().Sign() <= 0 {
+		return 0, false
+	}
+	return expectedBlockInterval(info.TotalStake().MLFloat() / total.MLFloat()), true
+}
+
+// notifyPoolDelegations announces new delegations to pools the user enabled
+// delegation alerts for, and withdrawals above the pool's threshold. The
+// delegations seen are stored before sending so a restart never repeats an
+// alert.
+func (a *App) notifyPoolDelegations(ctx context.Context, userID string, chatID int64, height int64) {
+	pools, err := a.store.GetDelegationAlertPools(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting delegation alert pools: %v", err)
+		return
+	}
+	poolIDs := make([]string, 0, len(pools))
+	for poolID := range pools {
+		poolIDs = append(poolIDs, poolID)
+	}
+	sort.Strings(poolIDs)
+
+	cycleCtx, stopCycle := context.WithCancel(ctx)
+	defer stopCycle()
+	for _, poolID := range poolIDs {
+		if cycleCtx.Err() != nil {
+			return
 		}
+		delegations, err := a.client.GetPoolDelegations(cycleCtx, poolID)
+		if err != nil {
+			logNotificationLookupError("pool delegations", poolID, err, stopCycle)
+			continue
+		}
+		lastSeen, err := a.store.GetPoolDelegationBalances(ctx, userID, poolID)
+		if err != nil {
+			log.Printf("Error fetching pool delegations: %v", err)
+			continue
+		}
+		balances := delegationBalances(delegations)
+		if sameBalances(lastSeen, balances) {
+			continue
+		}
+		changes := poolDelegationChanges(lastSeen, delegations, pools[poolID])
+		if err := a.store.ReplacePoolDelegationBalances(ctx, userID, poolID, balances); err != nil {
+			log.Printf("Error updating pool delegations: %v", err)
+			continue
+		}
+		for _, change := range changes {
+			if change.New {
+				a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: new delegation `%s` of %v%s", poolID, change.DelegationID, a.formatML(change.Amount), atBlock(a.heightFor(ctx, poolID, height))))
+			} else {
+				a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: `%s` withdrew %v%s", poolID, change.DelegationID, a.formatML(change.Amount), atBlock(a.heightFor(ctx, poolID, height))))
+			}
+		}
+	}
+}
+
+// atBlock is the suffix naming the tip height a change was seen at; it is
+// empty while the height is unknown.
+func atBlock(height int64) string {
+	if height <= 0 {
+		return ""
 	}
+	return fmt.Sprintf(" at block `%d`", height)
 }
 
-func (a *App) notifyDelegationsBalanceChanges(ctx context.Context, userID string, chatID int64) {
+func (a *App) notifyDelegationsBalanceChanges(ctx context.Context, userID string, chatID int64, height int64) {
 	delegations, err := a.store.GetDelegations(ctx, userID)
 	if err != nil {
 		log.Printf("Error getting delegations: %v", err)
 		return
 	}
 
+	cycleCtx, stopCycle := context.WithCancel(ctx)
+	defer stopCycle()
 	runTasksWithLimit(delegations, 10, func(delegationID string) {
 		/*start := delegationID[:10]
 		end := delegationID[len(delegationID)-10:]
 		printableDelegationID := start + "..." + end*/
 
-		new_balance, err := a.client.GetDelegationBalance(delegationID)
+		if cycleCtx.Err() != nil {
+			return
+		}
+		info, err := a.client.GetDelegationInfo(cycleCtx, delegationID)
 		if err != nil {
-			log.Printf("Error fetching balance: %v", err)
+			logNotificationLookupError("delegation", delegationID, err, stopCycle)
+			return
+		}
+		observed, ok := a.settledBalance(ctx, userID, observedDelegation, delegationID, info.Balance, a.observedHeight(ctx, delegationID, info.Height, height))
+		if !ok {
 			return
 		}
+		new_balance := observed.Balance
 		old_balance, err := a.store.GetDelegationBalance(ctx, userID, delegationID)
 		if err != nil {
 			log.Printf("Error fetching balance: %v", err)
 			return
 		}
-		if new_balance != old_balance {
+		if new_balance.Cmp(old_balance) != 0 {
 			err = a.store.UpdateDelegationBalance(ctx, userID, delegationID, new_balance)
 
-			p := message.NewPrinter(language.AmericanEnglish)
-			if new_balance >= old_balance {
-				a.sendMessage(ctx, a.bot, chatID, p.Sprintf("`%s`: \\+%v ML", delegationID, new_balance-old_balance))
+			rate := a.fiatRateFor(ctx, userID)
+			delta := new_balance.Sub(old_balance)
+			if delta.Sign() >= 0 {
+				a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: \\+%v%s%s", delegationID, a.formatML(delta), rate.suffix(delta), atBlock(observed.Height)))
 			} else {
-				a.sendMessage(ctx, a.bot, chatID, p.Sprintf("`%s`: \\-%v ML", delegationID, new_balance-old_balance))
+				a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: \\-%v%s%s", delegationID, a.formatML(delta.Abs()), rate.suffix(delta.Abs()), atBlock(observed.Height)))
 			}
 			if err != nil {
 				log.Printf("Error updating balance: %v", err)
@@ -767,21 +2129,32 @@ func (a *App) notifyDelegationsBalanceChanges(ctx context.Context, userID string
 	})
 }
 
-func (a *App) notifyPoolsBalanceChanges(ctx context.Context, userID string, chatID int64) {
+func (a *App) notifyPoolsBalanceChanges(ctx context.Context, userID string, chatID int64, height int64) {
 	pools, err := a.store.GetPools(ctx, userID)
 	if err != nil {
 		log.Printf("Error getting pools: %v", err)
 		return
 	}
 
+	cycleCtx, stopCycle := context.WithCancel(ctx)
+	defer stopCycle()
 	runTasksWithLimit(pools, 10, func(poolID string) {
 		/*start := poolID[:10]
 		end := poolID[len(poolID)-10:]
 		printablePoolID := start + "..." + end*/
 
-		new_balance, err := a.client.GetPoolBalance(poolID)
+		if cycleCtx.Err() != nil {
+			return
+		}
+		info, err := a.client.GetPoolInfo(cycleCtx, poolID)
 		if err != nil {
-			log.Printf("Error fetching balance: %v", err)
+			logNotificationLookupError("pool", poolID, err, stopCycle)
+			return
+		}
+		status := info.Status()
+		old_state, err := a.store.GetPoolState(ctx, userID, poolID)
+		if err != nil {
+			log.Printf("Error fetching pool state: %v", err)
 			return
 		}
 		old_balance, err := a.store.GetPoolBalance(ctx, userID, poolID)
@@ -789,14 +2162,58 @@ func (a *App) notifyPoolsBalanceChanges(ctx context.Context, userID string, chat
 			log.Printf("Error fetching balance: %v", err)
 			return
 		}
-		if new_balance != old_balance {
+
+		// Rows from before state tracking only have a non-zero balance to go
+		// by.
+		wasActive := old_state == PoolStateActive || (old_state == PoolStateUnknown && !old_balance.IsZero())
+		decommissioned := status.State == PoolStateDecommissioned && wasActive
+		// A decommission is stored once it is announced, below.
+		if status.State != old_state && !decommissioned {
+			if err := a.store.UpdatePoolState(ctx, userID, poolID, status.State); err != nil {
+				log.Printf("Error updating pool state: %v", err)
+				return
+			}
+		}
+		switch {
+		case status.State == PoolStateNotFound:
+			log.Printf("The pool %s was not found on the API server", poolID)
+			return
+		case status.State == PoolStateDecommissioned && !decommissioned:
+			return
+		}
+
+		// A decommission reads as a zero balance and waits for the same
+		// confirmations, so one that is reorganised away is never announced.
+		observed, ok := a.settledBalance(ctx, userID, observedPool, poolID, status.Balance, a.observedHeight(ctx, poolID, info.Height, height))
+		if !ok {
+			return
+		}
+		if decommissioned && observed.Balance.IsZero() {
+			if err := a.store.UpdatePoolState(ctx, userID, poolID, PoolStateDecommissioned); err != nil {
+				log.Printf("Error updating pool state: %v", err)
+				return
+			}
+			err = a.store.UpdatePoolBalance(ctx, userID, poolID, Amount{})
+			value := ""
+			if approx := a.fiatRateFor(ctx, userID).approx(old_balance); approx != "" {
+				value = ", " + approx
+			}
+			a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: `decommissioned` (\\-%v%s)%s", poolID, a.formatML(old_balance), value, atBlock(observed.Height)))
+			if err != nil {
+				log.Printf("Error updating balance: %v", err)
+			}
+			return
+		}
+		new_balance := observed.Balance
+		if new_balance.Cmp(old_balance) != 0 {
 			err = a.store.UpdatePoolBalance(ctx, userID, poolID, new_balance)
 
-			p := message.NewPrinter(language.AmericanEnglish)
-			if new_balance >= old_balance {
-				a.sendMessage(ctx, a.bot, chatID, p.Sprintf("`%s`: \\+%v ML", poolID, new_balance-old_balance))
+			rate := a.fiatRateFor(ctx, userID)
+			delta := new_balance.Sub(old_balance)
+			if delta.Sign() >= 0 {
+				a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: \\+%v%s%s", poolID, a.formatML(delta), rate.suffix(delta), atBlock(observed.Height)))
 			} else {
-				a.sendMessage(ctx, a.bot, chatID, p.Sprintf("`%s`: \\-%v ML", poolID, new_balance-old_balance))
+				a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: \\-%v%s%s", poolID, a.formatML(delta.Abs()), rate.suffix(delta.Abs()), atBlock(observed.Height)))
 			}
 
 			if err != nil {
@@ -807,6 +2224,183 @@ func (a *App) notifyPoolsBalanceChanges(ctx context.Context, userID string, chat
 	})
 }
 
+func (a *App) notifyAddressesBalanceChanges(ctx context.Context, userID string, chatID int64, height int64) {
+	addresses, err := a.store.GetMonitoredAddresses(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting addresses: %v", err)
+		return
+	}
+
+	monitored := make(map[string]MonitoredAddress, len(addresses))
+	ids := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		monitored[address.Address] = address
+		ids = append(ids, address.Address)
+	}
+
+	cycleCtx, stopCycle := context.WithCancel(ctx)
+	defer stopCycle()
+	runTasksWithLimit(ids, 10, func(address string) {
+		if cycleCtx.Err() != nil {
+			return
+		}
+		new_balance, err := a.client.GetAddressBalance(cycleCtx, address)
+		if err != nil {
+			logNotificationLookupError("address", address, err, stopCycle)
+			return
+		}
+		entry := monitored[address]
+		txIDs := unannouncedTxIDs(new_balance.TransactionIDs, entry.LastTxID, entry.Balance)
+		announced := a.notifyAddressCoinsChange(ctx, userID, chatID, height, entry, new_balance.Coins, txIDs)
+		if a.notifyAddressTokensChange(ctx, userID, chatID, height, address, new_balance.Tokens, txIDs) {
+			announced = true
+		}
+		if announced && len(new_balance.TransactionIDs) > 0 {
+			if err := a.store.UpdateAddressLastTx(ctx, userID, address, new_balance.TransactionIDs[0]); err != nil {
+				log.Printf("Error updating last transaction: %v", err)
+			}
+		}
+	})
+}
+
+// notifyAddressTransfers alerts on each transaction of the addresses with
+// transfer alerts on: when it shows up in the mempool, for API servers that
+// list unconfirmed transactions, and when it is confirmed. Alerts are
+// recorded before they are sent, and a confirmation only once, so neither a
+// restart nor a reorganisation repeats one.
+func (a *App) notifyAddressTransfers(ctx context.Context, userID string, chatID int64) {
+	addresses, err := a.store.GetTransferAlertAddresses(ctx, userID)
+	if err != nil {
+		log.Printf("Error getting transfer alert addresses: %v", err)
+		return
+	}
+
+	cycleCtx, stopCycle := context.WithCancel(ctx)
+	defer stopCycle()
+	for _, address := range addresses {
+		if cycleCtx.Err() != nil {
+			return
+		}
+		balance, err := a.client.GetAddressBalance(cycleCtx, address)
+		if err != nil {
+			logNotificationLookupError("address", address, err, stopCycle)
+			continue
+		}
+		alerted, err := a.store.GetAddressTransfers(ctx, userID, address)
+		if err != nil {
+			log.Printf("Error getting alerted transfers: %v", err)
+			continue
+		}
+		var pending []string
+		for _, txID := range balance.TransactionIDs {
+			if confirmed := alerted[txID]; !confirmed {
+				pending = append(pending, txID)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		// Oldest first, so alerts come in the order the transfers happened.
+		slices.Reverse(pending)
+		txs, err := a.client.GetAddressTransactions(cycleCtx, address, pending[:min(len(pending), transferAlertsPerCycle)])
+		if err != nil {
+			logNotificationLookupError("transactions of address", address, err, stopCycle)
+			continue
+		}
+		for _, tx := range txs {
+			confirmed := tx.BlockHeight > 0
+			if _, seen := alerted[tx.TxID]; seen && !confirmed {
+				continue
+			}
+			if err := a.store.MarkAddressTransfer(ctx, userID, address, tx.TxID, confirmed); err != nil {
+				log.Printf("Error recording transfer alert: %v", err)
+				continue
+			}
+			status := "in mempool"
+			if confirmed {
+				status = fmt.Sprintf("confirmed at block %d", tx.BlockHeight)
+			}
+			a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: %s, %s\nTransaction `%s`", address, a.formatTransfer(tx, address), status, tx.TxID))
+		}
+	}
+}
+
+// notifyAddressCoinsChange announces a change of the ML balance with the
+// transactions behind it and reports whether it did.
+func (a *App) notifyAddressCoinsChange(ctx context.Context, userID string, chatID int64, height int64, entry MonitoredAddress, new_balance Amount, txIDs []string) bool {
+	// The stored balance is the last announced one, so changes below the
+	// threshold accumulate until they are worth a message.
+	if !entry.announces(new_balance) {
+		return false
+	}
+	err := a.store.UpdateAddressBalance(ctx, userID, entry.Address, new_balance)
+
+	rate := a.fiatRateFor(ctx, userID)
+	delta := new_balance.Sub(entry.Balance)
+	if delta.Sign() >= 0 {
+		a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: \\+%v%s%s%s", entry.Address, a.formatML(delta), rate.suffix(delta), atBlock(a.heightFor(ctx, entry.Address, height)), formatTxIDs(txIDs)))
+	} else {
+		a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: \\-%v%s%s%s", entry.Address, a.formatML(delta.Abs()), rate.suffix(delta.Abs()), atBlock(a.heightFor(ctx, entry.Address, height)), formatTxIDs(txIDs)))
+	}
+	if err != nil {
+		log.Printf("Error updating balance: %v", err)
+	}
+	return true
+}
+
+// notifyAddressTokensChange announces every token balance change; the ML
+// threshold of the address does not apply to tokens. It reports whether
+// anything was announced.
+func (a *App) notifyAddressTokensChange(ctx context.Context, userID string, chatID int64, height int64, address string, tokens map[string]Amount, txIDs []string) bool {
+	old_tokens, err := a.store.GetAddressTokenBalances(ctx, userID, address)
+	if err != nil {
+		log.Printf("Error fetching token balances: %v", err)
+		return false
+	}
+	announced := false
+	for _, tokenID := range changedTokens(old_tokens, tokens) {
+		info, err := a.tokenInfo(ctx, tokenID)
+		if err != nil {
+			// Leave the stored balance alone so the change is retried.
+			log.Printf("Error fetching token %s: %v", tokenID, err)
+			continue
+		}
+		new_balance := tokens[tokenID]
+		err = a.store.UpdateAddressTokenBalance(ctx, userID, address, tokenID, new_balance)
+
+		delta := new_balance.Sub(old_tokens[tokenID])
+		if delta.Sign() >= 0 {
+			a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: \\+%v%s%s", address, info.Format(delta), atBlock(a.heightFor(ctx, address, height)), formatTxIDs(txIDs)))
+		} else {
+			a.sendMessage(ctx, a.bot, chatID, fmt.Sprintf("`%s`: \\-%v%s%s", address, info.Format(delta.Abs()), atBlock(a.heightFor(ctx, address, height)), formatTxIDs(txIDs)))
+		}
+		announced = true
+		if err != nil {
+			log.Printf("Error updating token balance: %v", err)
+		}
+	}
+	return announced
+}
+
+// logNotificationLookupError records a failed background lookup. The stored
+// balance is left untouched so a failure is never announced as a change; on
+// rate limiting the rest of the cycle is skipped.
+func logNotificationLookupError(kind, id string, err error, stopCycle context.CancelFunc) {
+	switch {
+	case errors.Is(err, context.Canceled):
+	case errors.Is(err, ErrRateLimited):
+		log.Printf("Rate limited fetching %s %s, skipping the rest of this cycle", kind, id)
+		stopCycle()
+	case errors.Is(err, ErrCircuitOpen):
+		log.Printf("API circuit breaker open fetching %s %s, skipping the rest of this cycle", kind, id)
+		stopCycle()
+	case errors.Is(err, ErrNotFound):
+		log.Printf("The %s %s was not found on the API server", kind, id)
+	default:
+		log.Printf("Error fetching balance of %s %s: %v", kind, id, err)
+	}
+}
+
 func (a *App) recoverPastNotifications(ctx context.Context) {
 	notifications, err := a.store.GetAllNotifications(ctx)
 
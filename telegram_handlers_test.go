@@ -186,12 +186,245 @@ func TestDebugCommandsUnauthorized(t *testing.T) {
 	}
 }
 
+func TestPoolInfoHandler(t *testing.T) {
+	const poolID = "mpool1qv9pzxqlyckngw6zf9g9whn9d3eh4qvgne3a4a"
+	client := &fakeBalanceClient{poolInfos: map[string]PoolInfo{
+		poolID: {
+			PoolID:                  poolID,
+			State:                   PoolStateActive,
+			StakerBalance:           AmountFromML(40000),
+			DelegationsBalance:      AmountFromAtoms(150_050_000_000_000),
+			MarginRatioPerThousand:  25,
+			CostPerBlock:            AmountFromML(50),
+			VRFPublicKey:            "vrfpub1abc",
+			DecommissionDestination: "mtc1decommission",
+		},
+	}}
+	app := NewApp(&fakeStore{}, client, nil, NewNotificationManager(), "", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Text: "/pool_info " + poolID,
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.poolInfoHandler(context.Background(), nil, update)
+	expected := "Pool `" + poolID + "`\n" +
+		"State: `active`\n" +
+		"Pledge: `40,000 ML`\n" +
+		"Delegations balance: `1,500.5 ML`\n" +
+		"Total stake: `41,500.5 ML`\n" +
+		"Margin ratio: `2.5%`\n" +
+		"Cost per block: `50 ML`\n" +
+		"VRF public key: `vrfpub1abc`\n" +
+		"Decommission key: `mtc1decommission`\n"
+	if lastMessage != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+}
+
+func TestDelegationInfoHandler(t *testing.T) {
+	const (
+		poolID       = "mpool1qv9pzxqlyckngw6zf9g9whn9d3eh4qvgne3a4a"
+		delegationID = "mdelg1qv9pzxqlyckngw6zf9g9whn9d3eh4qvg3d8nn4"
+	)
+	client := &fakeBalanceClient{
+		poolStates: map[string]PoolState{poolID: PoolStateDecommissioned},
+		delegationInfos: map[string]DelegationInfo{
+			delegationID: {
+				DelegationID:        delegationID,
+				PoolID:              poolID,
+				SpendDestination:    "mtc1owner",
+				CreationBlockHeight: 12345,
+				Balance:             AmountFromML(250),
+			},
+		},
+	}
+	app := NewApp(&fakeStore{}, client, nil, NewNotificationManager(), "", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Text: "/delegation_info " + delegationID,
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.delegationInfoHandler(context.Background(), nil, update)
+	expected := "Delegation `" + delegationID + "`\n" +
+		"Pool: `" + poolID + "`\n" +
+		"Pool state: `decommissioned`\n" +
+		"Balance: `250 ML`\n" +
+		"Spend destination: `mtc1owner`\n" +
+		"Created at block: `12345`\n"
+	if lastMessage != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+
+	update.Message.Text = "/delegation_info " + poolID
+	app.delegationInfoHandler(context.Background(), nil, update)
+	if lastMessage != "Invalid delegation ID: this is a mainnet pool ID, not a mainnet or testnet delegation ID" {
+		t.Fatalf("unexpected message for pool ID: %q", lastMessage)
+	}
+}
+
+func TestAddressAddHandlerWithoutAddress(t *testing.T) {
+	app := NewApp(&fakeStore{}, &noopBalanceClient{}, nil, NewNotificationManager(), "", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Text: "/address_add",
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.addressAddHandler(context.Background(), nil, update)
+	if lastMessage != "Usage: `/address_add <address> [threshold]`" {
+		t.Fatalf("unexpected message: %q", lastMessage)
+	}
+}
+
+func TestTokensHandlerAggregatesAcrossAddresses(t *testing.T) {
+	store := &fakeStore{addresses: []MonitoredAddress{{Address: "a1"}, {Address: "a2"}}}
+	client := &fakeBalanceClient{
+		addressBalances: map[string]Amount{"a1": {}, "a2": {}},
+		addressTokens: map[string]map[string]Amount{
+			"a1": {testMainnetTokenID: AmountFromAtoms(150), testMainnetTokenID2: AmountFromAtoms(7)},
+			// A pool ID posing as a token is not looked up.
+			"a2": {testMainnetTokenID: AmountFromAtoms(1_000_050), testMainnetPoolID: AmountFromAtoms(5)},
+		},
+		tokenInfos: map[string]TokenInfo{
+			testMainnetTokenID:  {TokenID: testMainnetTokenID, Ticker: "USDX", Decimals: 2},
+			testMainnetTokenID2: {TokenID: testMainnetTokenID2, Ticker: "ABC", Decimals: 0},
+			testMainnetPoolID:   {TokenID: testMainnetPoolID, Ticker: "BAD", Decimals: 0},
+		},
+	}
+	app := NewApp(store, client, nil, NewNotificationManager(), "", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Text: "/tokens",
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.tokensHandler(context.Background(), nil, update)
+	expected := "Your tokens:\n`ABC`: 7 \n`USDX`: 10,002 \n`" + testMainnetPoolID + "`: 5 \n"
+	if lastMessage != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+}
+
 type noopBalanceClient struct{}
 
-func (c *noopBalanceClient) GetPoolBalance(poolID string) (int64, error) {
-	return 0, nil
+func (c *noopBalanceClient) GetPoolStatus(ctx context.Context, poolID string) (PoolStatus, error) {
+	return PoolStatus{}, nil
+}
+
+func (c *noopBalanceClient) GetPoolInfo(ctx context.Context, poolID string) (PoolInfo, error) {
+	return PoolInfo{}, nil
+}
+
+func (c *noopBalanceClient) GetPoolDelegations(ctx context.Context, poolID string) ([]DelegationInfo, error) {
+	return nil, nil
+}
+
+func (c *noopBalanceClient) GetDelegationBalance(ctx context.Context, delegationID string) (Amount, error) {
+	return Amount{}, nil
+}
+
+func (c *noopBalanceClient) GetDelegationInfo(ctx context.Context, delegationID string) (DelegationInfo, error) {
+	return DelegationInfo{}, nil
 }
 
-func (c *noopBalanceClient) GetDelegationBalance(delegationID string) (int64, error) {
+func (c *noopBalanceClient) GetTipHeight(ctx context.Context) (int64, error) {
 	return 0, nil
 }
+
+func (c *noopBalanceClient) GetBlock(ctx context.Context, height int64) (BlockInfo, error) {
+	return BlockInfo{Height: height}, nil
+}
+
+func (c *noopBalanceClient) GetAddressBalance(ctx context.Context, address string) (AddressBalance, error) {
+	return AddressBalance{}, nil
+}
+
+func (c *noopBalanceClient) GetAddressHistory(ctx context.Context, address string, offset, count int) (AddressHistory, error) {
+	return AddressHistory{}, nil
+}
+
+func (c *noopBalanceClient) GetAddressTransactions(ctx context.Context, address string, txIDs []string) ([]TransactionInfo, error) {
+	return nil, nil
+}
+
+func (c *noopBalanceClient) GetAddressDelegations(ctx context.Context, address string) ([]DelegationInfo, error) {
+	return nil, nil
+}
+
+func (c *noopBalanceClient) GetAddressPools(ctx context.Context, address string) ([]PoolInfo, error) {
+	return nil, nil
+}
+
+func (c *noopBalanceClient) GetTotalStake(ctx context.Context, poolID string) (Amount, error) {
+	return Amount{}, nil
+}
+
+func (c *noopBalanceClient) GetTokenInfo(ctx context.Context, tokenID string) (TokenInfo, error) {
+	return TokenInfo{}, nil
+}
+
+func TestDebugAPIHandlerShowsEndpointInUse(t *testing.T) {
+	client := NewNetworkClient(Networks{{Name: mainnetNetwork, APIBaseURLs: []string{"http://one", "http://two"}}})
+	client.clients[mainnetNetwork].endpoints.mark("http://one", errors.New("connection refused"))
+	app := NewApp(&fakeStore{}, client, nil, NewNotificationManager(), "7", context.Background())
+
+	var lastMessage string
+	app.send = func(ctx context.Context, _ *bot.Bot, _ int64, message string) error {
+		lastMessage = message
+		return nil
+	}
+
+	update := &models.Update{
+		Message: &models.Message{
+			Text: "/debug_api",
+			Chat: models.Chat{ID: 5},
+			From: &models.User{ID: 7},
+		},
+	}
+
+	app.debugAPIHandler(context.Background(), nil, update)
+	expected := "API servers:\n" +
+		"mainnet `http://one`: unhealthy (`connection refused`)\n" +
+		"mainnet `http://two`: healthy, in use\n"
+	if lastMessage != expected {
+		t.Fatalf("unexpected message:\nexpected: %q\ngot:      %q", expected, lastMessage)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TokenInfo is the metadata of a fungible token needed to render amounts.
+type TokenInfo struct {
+	TokenID  string
+	Ticker   string
+	Decimals int
+}
+
+// DisplayTicker is the ticker made safe for use inside a Markdown code span,
+// falling back to the token ID when the token has no ticker.
+func (t TokenInfo) DisplayTicker() string {
+	ticker := strings.ReplaceAll(strings.TrimSpace(t.Ticker), "`", "'")
+	if ticker == "" {
+		return t.TokenID
+	}
+	return ticker
+}
+
+func (t TokenInfo) Format(amount Amount) string {
+	return amount.FormatUnits(t.Decimals, t.Decimals) + " " + t.DisplayTicker()
+}
+
+// tokenInfo looks up the metadata of a token an address holds. The token IDs
+// come from the API, so one that is not a token ID of a known network is
+// reported instead of looked up.
+func (a *App) tokenInfo(ctx context.Context, tokenID string) (TokenInfo, error) {
+	id, err := a.networks.ParseTokenID(tokenID)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("token %s: %w", tokenID, err)
+	}
+	return a.client.GetTokenInfo(ctx, id.String())
+}
+
+// AddressBalance is what an address holds: ML coins plus fungible tokens
+// keyed by token ID, in the token's own atoms. TransactionIDs is the
+// address's transaction history, newest first.
+type AddressBalance struct {
+	Coins          Amount
+	Tokens         map[string]Amount
+	TransactionIDs []string
+}
+
+// changedTokens returns the IDs of tokens whose amount differs between old
+// and new, sorted for stable output. Missing entries count as zero.
+func changedTokens(old, new map[string]Amount) []string {
+	var changed []string
+	for tokenID, amount := range new {
+		if amount.Cmp(old[tokenID]) != 0 {
+			changed = append(changed, tokenID)
+		}
+	}
+	for tokenID, amount := range old {
+		if _, ok := new[tokenID]; !ok && !amount.IsZero() {
+			changed = append(changed, tokenID)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
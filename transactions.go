@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// historyPageSize is how many transactions /address_history shows per page.
+const historyPageSize = 10
+
+// transferAlertsPerCycle caps the transactions of one address looked up per
+// notification cycle; the rest are alerted in the following cycles.
+const transferAlertsPerCycle = 20
+
+// notifiedTxIDsShown caps the transaction IDs listed in a balance
+// notification.
+const notifiedTxIDsShown = 5
+
+// TxTransfer is ML coins spent from or paid to a destination. Destination is
+// the delegation ID for coins delegated to a pool.
+type TxTransfer struct {
+	Destination string
+	Coins       Amount
+}
+
+// TransactionInfo is the coin movement of a transaction returned by
+// /api/v2/transaction/{id}. Inputs are the outputs it spends. BlockHeight is
+// 0 while the transaction is in the mempool.
+type TransactionInfo struct {
+	TxID        string
+	BlockHeight int64
+	Timestamp   time.Time
+	Inputs      []TxTransfer
+	Outputs     []TxTransfer
+}
+
+// AddressHistory is one page of an address's transactions, newest first,
+// and how many transactions the address has in total.
+type AddressHistory struct {
+	Total        int
+	Transactions []TransactionInfo
+}
+
+// transferFor returns how much the transaction changed the ML balance of
+// address, and the other side of the transfer: the addresses paying in for
+// an incoming transaction, the ones paid for an outgoing one. The fee makes a
+// transfer between the address and itself a small outgoing one.
+func (t TransactionInfo) transferFor(address string) (Amount, []string) {
+	var received, spent Amount
+	var payers, payees []string
+	for _, input := range t.Inputs {
+		if input.Destination == address {
+			spent = spent.Add(input.Coins)
+		} else {
+			payers = appendUnique(payers, input.Destination)
+		}
+	}
+	for _, output := range t.Outputs {
+		if output.Destination == address {
+			received = received.Add(output.Coins)
+		} else {
+			payees = appendUnique(payees, output.Destination)
+		}
+	}
+	delta := received.Sub(spent)
+	if delta.Sign() >= 0 {
+		return delta, payers
+	}
+	return delta, payees
+}
+
+func appendUnique(list []string, value string) []string {
+	if value == "" || slices.Contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
+
+// formatCounterparties renders the first counterparty and how many others
+// there are, e.g. "`tmt1…` and 2 others".
+func formatCounterparties(counterparties []string) string {
+	switch len(counterparties) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("`%s`", counterparties[0])
+	case 2:
+		return fmt.Sprintf("`%s` and 1 other", counterparties[0])
+	default:
+		return fmt.Sprintf("`%s` and %d others", counterparties[0], len(counterparties)-1)
+	}
+}
+
+// unannouncedTxIDs returns the transactions in history, newest first, that
+// came after lastTxID, the newest one already announced. Before anything was
+// announced the whole history is new for an address announced empty, and
+// unknown otherwise.
+func unannouncedTxIDs(history []string, lastTxID string, announcedBalance Amount) []string {
+	if lastTxID == "" {
+		if announcedBalance.IsZero() {
+			return history
+		}
+		return nil
+	}
+	for i, txID := range history {
+		if txID == lastTxID {
+			return history[:i]
+		}
+	}
+	// The last announced transaction is gone, e.g. reorganised away.
+	return nil
+}
+
+// formatTxIDs lists transaction IDs for a notification, capped at
+// notifiedTxIDsShown.
+func formatTxIDs(txIDs []string) string {
+	if len(txIDs) == 0 {
+		return ""
+	}
+	shown := txIDs[:min(len(txIDs), notifiedTxIDsShown)]
+	msg := "\nTransactions:"
+	for _, txID := range shown {
+		msg += fmt.Sprintf(" `%s`", txID)
+	}
+	if more := len(txIDs) - len(shown); more > 0 {
+		msg += fmt.Sprintf(" and %d more", more)
+	}
+	return msg
+}